@@ -0,0 +1,261 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the v1alpha1 version of the configuration file
+// of the controller manager.
+package v1alpha1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// GroupVersion is the apiVersion of the configuration file.
+	GroupVersion = "config.certmanager.awspca/v1alpha1"
+	// Kind is the kind of the configuration file.
+	Kind = "ControllerManagerConfig"
+)
+
+// ControllerManagerConfig configures the controller manager. Every field
+// has a command line flag, which overrides the value of the file.
+type ControllerManagerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// LeaderElection configures the election of the active replica of the
+	// controller manager.
+	// +optional
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+
+	// Namespaces are the namespaces watched by the controllers, all the
+	// namespaces if empty.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterResourceNamespace is the namespace of the resources of the
+	// controller manager that do not belong to an issuer, such as the
+	// leader election lock when LeaderElection.ResourceNamespace is not set.
+	// Defaults to the namespace the controller manager runs in.
+	// +optional
+	ClusterResourceNamespace string `json:"clusterResourceNamespace,omitempty"`
+
+	// MetricsBindAddress is the address the metrics endpoint binds to.
+	// +optional
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+
+	// HealthProbeBindAddress is the address the health probes bind to,
+	// they are disabled if empty.
+	// +optional
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+
+	// AWSReadinessCheck makes the readiness probe fail when every issuer
+	// failed its last verification with AWS Private CA. The webhooks are
+	// then unavailable too, as they are served by the same pod.
+	// +optional
+	AWSReadinessCheck bool `json:"awsReadinessCheck,omitempty"`
+
+	// DebugBindAddress is the address the debug endpoints bind to, they are
+	// disabled if empty.
+	// +optional
+	DebugBindAddress string `json:"debugBindAddress,omitempty"`
+
+	// Webhook configures the webhook server.
+	// +optional
+	Webhook WebhookConfig `json:"webhook,omitempty"`
+
+	// Concurrency is the maximum number of concurrent reconciliations of
+	// each controller.
+	// +optional
+	Concurrency ConcurrencyConfig `json:"concurrency,omitempty"`
+
+	// AWSTimeout is the deadline of the AWS Private CA calls of a
+	// reconciliation, including the wait for a certificate to be issued.
+	// Zero disables the deadline.
+	// +optional
+	AWSTimeout metav1.Duration `json:"awsTimeout,omitempty"`
+
+	// AWSEndpoint is the URL of the AWS Private CA API used instead of the
+	// regional endpoints of AWS, e.g. a mock server for tests.
+	// +optional
+	AWSEndpoint string `json:"awsEndpoint,omitempty"`
+
+	// CAExpiryWarningThreshold is the remaining validity of a private CA
+	// certificate below which issuers report the CAExpiringSoon condition.
+	// +optional
+	CAExpiryWarningThreshold metav1.Duration `json:"caExpiryWarningThreshold,omitempty"`
+
+	// CAExpiryCheckInterval is how often the expiry of the private CA of
+	// each issuer is checked. Zero disables the periodic check.
+	// +optional
+	CAExpiryCheckInterval metav1.Duration `json:"caExpiryCheckInterval,omitempty"`
+
+	// AllowAmbientCredentials lets the issuers without an AWS credentials
+	// secret use the default AWS credential chain of the controller
+	// manager, e.g. its IAM role. Anyone who can create an issuer is then
+	// granted the permissions of that role.
+	// +optional
+	AllowAmbientCredentials bool `json:"allowAmbientCredentials,omitempty"`
+
+	// DisableApprovalCheck signs CertificateRequests without waiting for
+	// them to be approved.
+	// +optional
+	DisableApprovalCheck bool `json:"disableApprovalCheck,omitempty"`
+
+	// AuditSink is where the audit record of every sign attempt is
+	// written: stdout, file:<path> or resource.
+	// +optional
+	AuditSink string `json:"auditSink,omitempty"`
+
+	// TraceExporter is where the OpenTelemetry spans of the reconciliations
+	// and AWS calls are exported: stdout or otlp:<url>. Tracing is disabled
+	// if empty.
+	// +optional
+	TraceExporter string `json:"traceExporter,omitempty"`
+
+	// RateLimits limits the requests of the controller manager to the
+	// Kubernetes API.
+	// +optional
+	RateLimits RateLimitsConfig `json:"rateLimits,omitempty"`
+
+	// LogLevel is the minimum level of the logs, one of ('error', 'info',
+	// 'debug'). Debug includes the verbose logs of the controllers.
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+}
+
+// LeaderElectionConfig configures the leader election.
+type LeaderElectionConfig struct {
+	// LeaderElect enables the leader election, so that only one replica of
+	// the controller manager is active.
+	// +optional
+	LeaderElect bool `json:"leaderElect,omitempty"`
+
+	// ResourceNamespace is the namespace of the leader election lock.
+	// Defaults to ClusterResourceNamespace.
+	// +optional
+	ResourceNamespace string `json:"resourceNamespace,omitempty"`
+
+	// ResourceName is the name of the leader election lock.
+	// +optional
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// WebhookConfig configures the webhook server.
+type WebhookConfig struct {
+	// Enable enables the conversion, defaulting and validating webhooks.
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+
+	// Port is the port the webhook server binds to.
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// CertDir is the directory of the serving certificate of the webhook
+	// server, tls.crt and tls.key.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+}
+
+// ConcurrencyConfig is the maximum number of concurrent reconciliations of
+// each controller.
+type ConcurrencyConfig struct {
+	// AWSPCAIssuer is the concurrency of the AWSPCAIssuer controller.
+	// +optional
+	AWSPCAIssuer int `json:"awsPCAIssuer,omitempty"`
+
+	// CertificateRequest is the concurrency of the CertificateRequest
+	// controller.
+	// +optional
+	CertificateRequest int `json:"certificateRequest,omitempty"`
+
+	// AWSPCAIssuedCertificate is the concurrency of the
+	// AWSPCAIssuedCertificate controller.
+	// +optional
+	AWSPCAIssuedCertificate int `json:"awsPCAIssuedCertificate,omitempty"`
+}
+
+// RateLimitsConfig limits the requests to the Kubernetes API.
+type RateLimitsConfig struct {
+	// QPS is the sustained number of queries per second to the Kubernetes
+	// API. The client default is used if zero.
+	// +optional
+	QPS float32 `json:"qps,omitempty"`
+
+	// Burst is the maximum burst of queries to the Kubernetes API. The
+	// client default is used if zero.
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+// Load reads the configuration file at the given path into config. The
+// fields missing from the file keep their value, e.g. the defaults of the
+// flags. The configuration is not validated, so that the flags can still
+// override invalid values of the file.
+func Load(path string, config *ControllerManagerConfig) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	if meta.APIVersion != GroupVersion || meta.Kind != Kind {
+		return fmt.Errorf("%s is a %s %s, not a %s %s", path, meta.APIVersion, meta.Kind, GroupVersion, Kind)
+	}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c *ControllerManagerConfig) Validate() error {
+	var errs []string
+	switch c.LogLevel {
+	case "", "error", "info", "debug":
+	default:
+		errs = append(errs, fmt.Sprintf("logLevel: unsupported value %q, must be one of error, info or debug", c.LogLevel))
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+	}{
+		{"concurrency.awsPCAIssuer", c.Concurrency.AWSPCAIssuer},
+		{"concurrency.certificateRequest", c.Concurrency.CertificateRequest},
+		{"concurrency.awsPCAIssuedCertificate", c.Concurrency.AWSPCAIssuedCertificate},
+		{"rateLimits.burst", c.RateLimits.Burst},
+		{"webhook.port", c.Webhook.Port},
+	} {
+		if f.value < 0 {
+			errs = append(errs, fmt.Sprintf("%s: must not be negative", f.name))
+		}
+	}
+	if c.RateLimits.QPS < 0 {
+		errs = append(errs, "rateLimits.qps: must not be negative")
+	}
+	if c.AWSTimeout.Duration < 0 {
+		errs = append(errs, "awsTimeout: must not be negative")
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
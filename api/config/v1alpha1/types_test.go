@@ -0,0 +1,110 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoad(t *testing.T) {
+	defaults := ControllerManagerConfig{
+		MetricsBindAddress: ":8080",
+		AWSTimeout:         metav1.Duration{Duration: 2 * time.Minute},
+		LogLevel:           "info",
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    ControllerManagerConfig
+		wantErr string
+	}{
+		{
+			name: "overrides the defaults",
+			data: `apiVersion: config.certmanager.awspca/v1alpha1
+kind: ControllerManagerConfig
+leaderElection:
+  leaderElect: true
+  resourceNamespace: kube-system
+namespaces: [team-a, team-b]
+awsTimeout: 30s
+concurrency:
+  certificateRequest: 4
+rateLimits:
+  qps: 50
+  burst: 100
+logLevel: debug
+`,
+			want: ControllerManagerConfig{
+				TypeMeta:           metav1.TypeMeta{APIVersion: GroupVersion, Kind: Kind},
+				LeaderElection:     LeaderElectionConfig{LeaderElect: true, ResourceNamespace: "kube-system"},
+				Namespaces:         []string{"team-a", "team-b"},
+				MetricsBindAddress: ":8080",
+				AWSTimeout:         metav1.Duration{Duration: 30 * time.Second},
+				Concurrency:        ConcurrencyConfig{CertificateRequest: 4},
+				RateLimits:         RateLimitsConfig{QPS: 50, Burst: 100},
+				LogLevel:           "debug",
+			},
+		},
+		{
+			name:    "wrong kind",
+			data:    "apiVersion: config.certmanager.awspca/v1alpha1\nkind: Other\n",
+			wantErr: "not a config.certmanager.awspca/v1alpha1 ControllerManagerConfig",
+		},
+		{
+			name:    "unknown field",
+			data:    "apiVersion: config.certmanager.awspca/v1alpha1\nkind: ControllerManagerConfig\nmetricsAddr: :9090\n",
+			wantErr: `unknown field "metricsAddr"`,
+		},
+		{
+			name:    "invalid values",
+			data:    "apiVersion: config.certmanager.awspca/v1alpha1\nkind: ControllerManagerConfig\nlogLevel: trace\nconcurrency:\n  awsPCAIssuer: -1\n",
+			wantErr: "logLevel: unsupported value \"trace\", must be one of error, info or debug, concurrency.awsPCAIssuer: must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got := defaults
+			err := Load(path, &got)
+			if err == nil {
+				err = got.Validate()
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/awspca-issuer/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// hubDataAnnotation holds the v1beta1 fields that cannot be represented in
+// v1alpha2, so they are not lost when a resource is read and written back
+// as v1alpha2.
+const hubDataAnnotation = "certmanager.awspca/v1beta1-data"
+
+// hubData contains the v1beta1 fields stored in the hubDataAnnotation.
+type hubData struct {
+	CertificateAuthorities        []v1beta1.CertificateAuthority       `json:"certificateAuthorities,omitempty"`
+	TemplateArn                   string                               `json:"templateArn,omitempty"`
+	Subject                       *v1beta1.SubjectTemplate             `json:"subject,omitempty"`
+	CSRPolicy                     *v1beta1.CSRPolicy                   `json:"csrPolicy,omitempty"`
+	SPIFFE                        *v1beta1.SPIFFEConfig                `json:"spiffe,omitempty"`
+	TrustBundle                   *v1beta1.TrustBundle                 `json:"trustBundle,omitempty"`
+	RetryPolicy                   *v1beta1.RetryPolicy                 `json:"retryPolicy,omitempty"`
+	CertificateAuthoritiesStatus  []v1beta1.CertificateAuthorityStatus `json:"certificateAuthoritiesStatus,omitempty"`
+	RetiredCertificateAuthorities []string                             `json:"retiredCertificateAuthorities,omitempty"`
+	CABundle                      []byte                               `json:"caBundle,omitempty"`
+}
+
+var _ conversion.Convertible = &AWSPCAIssuer{}
+
+// ConvertTo converts this AWSPCAIssuer to the hub version (v1beta1).
+func (src *AWSPCAIssuer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.AWSPCAIssuer)
+	dst.ObjectMeta = src.ObjectMeta
+
+	p := src.Spec.Provisioner
+	dst.Spec = v1beta1.AWSPCAIssuerSpec{
+		Arn:                      p.Arn,
+		Region:                   p.Region,
+		CAExpiryWarningThreshold: src.Spec.CAExpiryWarningThreshold,
+	}
+	// The secret was mandatory in v1alpha2, an empty provisioner means the
+	// default AWS credential chain is used.
+	if p.Name != "" || p.AccessKeyRef.Key != "" || p.SecretKeyRef.Key != "" || p.RegionRef.Key != "" || p.ArnRef.Key != "" {
+		dst.Spec.SecretRef = &v1beta1.AWSCredentialsSecretReference{
+			Name:         p.Name,
+			AccessKeyRef: v1beta1.SecretKeySelector{Key: p.AccessKeyRef.Key},
+			SecretKeyRef: v1beta1.SecretKeySelector{Key: p.SecretKeyRef.Key},
+			RegionRef:    v1beta1.SecretKeySelector{Key: p.RegionRef.Key},
+			ArnRef:       v1beta1.SecretKeySelector{Key: p.ArnRef.Key},
+		}
+	}
+
+	dst.Status.Conditions = nil
+	for _, c := range src.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, v1beta1.AWSPCAIssuerCondition{
+			Type:               v1beta1.ConditionType(c.Type),
+			Status:             v1beta1.ConditionStatus(c.Status),
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             v1beta1.ConditionReason(c.Reason),
+			Message:            c.Message,
+			ObservedGeneration: c.ObservedGeneration,
+		})
+	}
+
+	if data, ok := src.Annotations[hubDataAnnotation]; ok {
+		var hub hubData
+		if err := json.Unmarshal([]byte(data), &hub); err != nil {
+			return fmt.Errorf("error decoding annotation %s: %v", hubDataAnnotation, err)
+		}
+		dst.Spec.CertificateAuthorities = hub.CertificateAuthorities
+		dst.Spec.TemplateArn = hub.TemplateArn
+		dst.Spec.Subject = hub.Subject
+		dst.Spec.CSRPolicy = hub.CSRPolicy
+		dst.Spec.SPIFFE = hub.SPIFFE
+		dst.Spec.TrustBundle = hub.TrustBundle
+		dst.Spec.RetryPolicy = hub.RetryPolicy
+		dst.Status.CertificateAuthorities = hub.CertificateAuthoritiesStatus
+		dst.Status.RetiredCertificateAuthorities = hub.RetiredCertificateAuthorities
+		dst.Status.CABundle = hub.CABundle
+		dst.Annotations = withoutAnnotation(src.Annotations, hubDataAnnotation)
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1beta1) to this version.
+func (dst *AWSPCAIssuer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.AWSPCAIssuer)
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec = AWSPCAIssuerSpec{
+		Provisioner: AWSPCAProvisioner{
+			Arn:    src.Spec.Arn,
+			Region: src.Spec.Region,
+		},
+		CAExpiryWarningThreshold: src.Spec.CAExpiryWarningThreshold,
+	}
+	if ref := src.Spec.SecretRef; ref != nil {
+		dst.Spec.Provisioner.Name = ref.Name
+		dst.Spec.Provisioner.AccessKeyRef = SecretKeySelector{Key: ref.AccessKeyRef.Key}
+		dst.Spec.Provisioner.SecretKeyRef = SecretKeySelector{Key: ref.SecretKeyRef.Key}
+		dst.Spec.Provisioner.RegionRef = SecretKeySelector{Key: ref.RegionRef.Key}
+		dst.Spec.Provisioner.ArnRef = SecretKeySelector{Key: ref.ArnRef.Key}
+	}
+
+	dst.Status.Conditions = nil
+	for _, c := range src.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, AWSPCAIssuerCondition{
+			Type:               ConditionType(c.Type),
+			Status:             ConditionStatus(c.Status),
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             ConditionReason(c.Reason),
+			Message:            c.Message,
+			ObservedGeneration: c.ObservedGeneration,
+		})
+	}
+
+	hub := hubData{
+		CertificateAuthorities:        src.Spec.CertificateAuthorities,
+		TemplateArn:                   src.Spec.TemplateArn,
+		Subject:                       src.Spec.Subject,
+		CSRPolicy:                     src.Spec.CSRPolicy,
+		SPIFFE:                        src.Spec.SPIFFE,
+		TrustBundle:                   src.Spec.TrustBundle,
+		RetryPolicy:                   src.Spec.RetryPolicy,
+		CertificateAuthoritiesStatus:  src.Status.CertificateAuthorities,
+		RetiredCertificateAuthorities: src.Status.RetiredCertificateAuthorities,
+		CABundle:                      src.Status.CABundle,
+	}
+	if len(hub.CertificateAuthorities) > 0 || hub.TemplateArn != "" || hub.Subject != nil || hub.CSRPolicy != nil || hub.SPIFFE != nil || hub.TrustBundle != nil || hub.RetryPolicy != nil ||
+		len(hub.CertificateAuthoritiesStatus) > 0 || len(hub.RetiredCertificateAuthorities) > 0 || len(hub.CABundle) > 0 {
+		data, err := json.Marshal(hub)
+		if err != nil {
+			return fmt.Errorf("error encoding annotation %s: %v", hubDataAnnotation, err)
+		}
+		dst.Annotations = withAnnotation(src.Annotations, hubDataAnnotation, string(data))
+	}
+	return nil
+}
+
+// withAnnotation returns a copy of the given annotations with the key set to
+// value. The annotations of the source object must not be modified.
+func withAnnotation(annotations map[string]string, key, value string) map[string]string {
+	out := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+// withoutAnnotation returns a copy of the given annotations without the key,
+// or nil if no annotations are left.
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	var out map[string]string
+	for k, v := range annotations {
+		if k == key {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(annotations))
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/awspca-issuer/api/v1beta1"
+	fuzz "github.com/google/gofuzz"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+const fuzzIterations = 1000
+
+func newFuzzer(t *testing.T) *fuzz.Fuzzer {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, func(serializer.CodecFactory) []interface{} {
+		return []interface{}{
+			// The apiVersion and kind are set by the conversion webhook, not
+			// by the conversion functions.
+			func(in *metav1.TypeMeta, c fuzz.Continue) {
+				*in = metav1.TypeMeta{}
+			},
+			// A v1beta1 secret reference always has a name, the schema
+			// requires it.
+			func(in *v1beta1.AWSCredentialsSecretReference, c fuzz.Continue) {
+				c.FuzzNoCustom(in)
+				if in.Name == "" {
+					in.Name = "aws-credentials"
+				}
+			},
+		}
+	})
+	return fuzzer.FuzzerFor(funcs, rand.NewSource(rand.Int63()), serializer.NewCodecFactory(scheme))
+}
+
+func TestAWSPCAIssuerConversionRoundTrip(t *testing.T) {
+	f := newFuzzer(t)
+
+	t.Run("v1alpha2 to v1beta1 to v1alpha2", func(t *testing.T) {
+		for i := 0; i < fuzzIterations; i++ {
+			src := &AWSPCAIssuer{}
+			f.Fuzz(src)
+
+			hub := &v1beta1.AWSPCAIssuer{}
+			if err := src.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+			dst := &AWSPCAIssuer{}
+			if err := dst.ConvertFrom(hub); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+
+			if !apiequality.Semantic.DeepEqual(src, dst) {
+				t.Fatalf("round trip mismatch:\n%s", diff.ObjectReflectDiff(src, dst))
+			}
+		}
+	})
+
+	t.Run("v1beta1 to v1alpha2 to v1beta1", func(t *testing.T) {
+		for i := 0; i < fuzzIterations; i++ {
+			src := &v1beta1.AWSPCAIssuer{}
+			f.Fuzz(src)
+
+			spoke := &AWSPCAIssuer{}
+			if err := spoke.ConvertFrom(src); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+			dst := &v1beta1.AWSPCAIssuer{}
+			if err := spoke.ConvertTo(dst); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+
+			if !apiequality.Semantic.DeepEqual(src, dst) {
+				t.Fatalf("round trip mismatch:\n%s", diff.ObjectReflectDiff(src, dst))
+			}
+		}
+	})
+}
+
+func TestAWSPCAIssuerConvertTo(t *testing.T) {
+	src := &AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+		Spec: AWSPCAIssuerSpec{
+			Provisioner: AWSPCAProvisioner{
+				Name:         "aws-credentials",
+				AccessKeyRef: SecretKeySelector{Key: "accesskey"},
+				SecretKeyRef: SecretKeySelector{Key: "secretkey"},
+				RegionRef:    SecretKeySelector{Key: "region"},
+				ArnRef:       SecretKeySelector{Key: "arn"},
+			},
+		},
+	}
+	want := &v1beta1.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+		Spec: v1beta1.AWSPCAIssuerSpec{
+			SecretRef: &v1beta1.AWSCredentialsSecretReference{
+				Name:         "aws-credentials",
+				AccessKeyRef: v1beta1.SecretKeySelector{Key: "accesskey"},
+				SecretKeyRef: v1beta1.SecretKeySelector{Key: "secretkey"},
+				RegionRef:    v1beta1.SecretKeySelector{Key: "region"},
+				ArnRef:       v1beta1.SecretKeySelector{Key: "arn"},
+			},
+		},
+	}
+
+	dst := &v1beta1.AWSPCAIssuer{}
+	if err := src.ConvertTo(dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(want, dst) {
+		t.Errorf("ConvertTo() mismatch:\n%s", diff.ObjectReflectDiff(want, dst))
+	}
+
+	// Fields that only exist in v1beta1 are restored from the annotation
+	// set when converting from v1beta1.
+	hub := &v1beta1.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}},
+		Spec: v1beta1.AWSPCAIssuerSpec{
+			CertificateAuthorities: []v1beta1.CertificateAuthority{{Arn: "primary"}, {Arn: "secondary", Region: "us-west-2"}},
+		},
+		Status: v1beta1.AWSPCAIssuerStatus{
+			CertificateAuthorities: []v1beta1.CertificateAuthorityStatus{{Arn: "primary", Ready: v1beta1.ConditionTrue}},
+		},
+	}
+	spoke := &AWSPCAIssuer{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+	if _, ok := spoke.Annotations[hubDataAnnotation]; !ok {
+		t.Errorf("ConvertFrom() did not set annotation %s", hubDataAnnotation)
+	}
+	if _, ok := hub.Annotations[hubDataAnnotation]; ok {
+		t.Errorf("ConvertFrom() modified the annotations of the source")
+	}
+	dst = &v1beta1.AWSPCAIssuer{}
+	if err := spoke.ConvertTo(dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(hub, dst) {
+		t.Errorf("ConvertTo() mismatch:\n%s", diff.ObjectReflectDiff(hub, dst))
+	}
+
+	// Without a secret the issuer uses the default AWS credential chain.
+	src = &AWSPCAIssuer{Spec: AWSPCAIssuerSpec{Provisioner: AWSPCAProvisioner{Arn: "arn"}}}
+	dst = &v1beta1.AWSPCAIssuer{}
+	if err := src.ConvertTo(dst); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if dst.Spec.SecretRef != nil || dst.Spec.Arn != "arn" {
+		t.Errorf("ConvertTo() = %+v, want only spec.arn set", dst.Spec)
+	}
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package v1alpha2
 
 import (
+	"github.com/awspca-issuer/api/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,6 +34,12 @@ type AWSPCAIssuerSpec struct {
 
 	// Provisioner contains the AWS Private CA certificates provisioner configuration.
 	Provisioner AWSPCAProvisioner `json:"provisioner"`
+
+	// CAExpiryWarningThreshold is the remaining validity of the private CA
+	// certificate below which the CAExpiringSoon condition is set to True.
+	// Defaults to the --ca-expiry-warning-threshold flag of the controller.
+	// +optional
+	CAExpiryWarningThreshold *metav1.Duration `json:"caExpiryWarningThreshold,omitempty"`
 }
 
 // AWSCMIssuerStatus defines the observed state of AWSCMIssuer
@@ -77,26 +84,66 @@ type AWSPCAProvisioner struct {
 	// The name of the secret in the pod's namespace to select from.
 	Name string `json:"name"`
 
-	// Reference to AWS access key
-	AccessKeyRef SecretKeySelector `json:"accesskeyRef"`
+	// Reference to AWS access key, the key defaults to 'accesskey'.
+	// +optional
+	AccessKeyRef SecretKeySelector `json:"accesskeyRef,omitempty"`
 
-	// Reference to AWS secret key
-	SecretKeyRef SecretKeySelector `json:"secretkeyRef"`
+	// Reference to AWS secret key, the key defaults to 'secretkey'.
+	// +optional
+	SecretKeyRef SecretKeySelector `json:"secretkeyRef,omitempty"`
 
-	// Reference to AWS region
-	RegionRef SecretKeySelector `json:"regionRef"`
+	// AWS region of the private CA. If not set, the region is read from the
+	// secret using RegionRef, or derived from the private CA ARN.
+	// +optional
+	Region string `json:"region,omitempty"`
 
-	// Reference to private CA ARN
-	ArnRef SecretKeySelector `json:"arnRef"`
+	// ARN of the private CA. If not set, the ARN is read from the secret using
+	// ArnRef.
+	// +optional
+	Arn string `json:"arn,omitempty"`
+
+	// Reference to AWS region, used if Region is not set. The key defaults to
+	// 'region' if neither Region nor Arn are set.
+	// +optional
+	RegionRef SecretKeySelector `json:"regionRef,omitempty"`
+
+	// Reference to private CA ARN, used if Arn is not set. The key defaults to
+	// 'arn' if Arn is not set.
+	// +optional
+	ArnRef SecretKeySelector `json:"arnRef,omitempty"`
 }
 
 // ConditionType represents a AWSPCAIssuer condition type.
-// +kubebuilder:validation:Enum=Ready
+// +kubebuilder:validation:Enum=Ready;CredentialsValid;CAReachable;CAActive;CAExpiringSoon;PolicyValid
 type ConditionType string
 
 const (
-	// ConditionReady indicates that a AWSPCAIssuer is ready for use.
+	// ConditionReady indicates that a AWSPCAIssuer is ready for use. It is
+	// the conjunction of the other conditions: it is only True if
+	// CredentialsValid, CAReachable, CAActive and PolicyValid are True and
+	// the private CA certificate has not expired.
 	ConditionReady ConditionType = "Ready"
+
+	// ConditionCredentialsValid indicates that the AWS credentials of a
+	// AWSPCAIssuer could be loaded and are accepted by AWS.
+	ConditionCredentialsValid ConditionType = "CredentialsValid"
+
+	// ConditionCAReachable indicates that at least one of the private CAs
+	// of a AWSPCAIssuer could be described.
+	ConditionCAReachable ConditionType = "CAReachable"
+
+	// ConditionCAActive indicates that at least one of the private CAs of a
+	// AWSPCAIssuer that is not draining is active.
+	ConditionCAActive ConditionType = "CAActive"
+
+	// ConditionCAExpiringSoon indicates that the certificate of the private
+	// CA used by a AWSPCAIssuer is close to its expiry. Certificates issued
+	// by the CA cannot outlive it, so their validity shortens as it nears.
+	ConditionCAExpiringSoon ConditionType = "CAExpiringSoon"
+
+	// ConditionPolicyValid indicates that the spec of a AWSPCAIssuer is
+	// valid.
+	ConditionPolicyValid ConditionType = "PolicyValid"
 )
 
 // ConditionStatus represents a condition's status.
@@ -119,9 +166,53 @@ const (
 	ConditionUnknown ConditionStatus = "Unknown"
 )
 
+// ConditionReason is a brief machine readable explanation for the status of
+// a condition. The reasons are the same in every version of the API.
+type ConditionReason string
+
+// The reasons of the conditions and Events set by the controllers. They are
+// defined, and documented, by the hub version v1beta1.
+const (
+	ReasonVerified    = ConditionReason(v1beta1.ReasonVerified)
+	ReasonValidation  = ConditionReason(v1beta1.ReasonValidation)
+	ReasonNotFound    = ConditionReason(v1beta1.ReasonNotFound)
+	ReasonSecretError = ConditionReason(v1beta1.ReasonSecretError)
+	ReasonError       = ConditionReason(v1beta1.ReasonError)
+
+	ReasonActive       = ConditionReason(v1beta1.ReasonActive)
+	ReasonNotActive    = ConditionReason(v1beta1.ReasonNotActive)
+	ReasonCheckFailed  = ConditionReason(v1beta1.ReasonCheckFailed)
+	ReasonValid        = ConditionReason(v1beta1.ReasonValid)
+	ReasonExpiringSoon = ConditionReason(v1beta1.ReasonExpiringSoon)
+	ReasonExpired      = ConditionReason(v1beta1.ReasonExpired)
+
+	ReasonAccepted    = ConditionReason(v1beta1.ReasonAccepted)
+	ReasonRejected    = ConditionReason(v1beta1.ReasonRejected)
+	ReasonReachable   = ConditionReason(v1beta1.ReasonReachable)
+	ReasonUnreachable = ConditionReason(v1beta1.ReasonUnreachable)
+	ReasonNotChecked  = ConditionReason(v1beta1.ReasonNotChecked)
+
+	ReasonCAExpiringSoon    = ConditionReason(v1beta1.ReasonCAExpiringSoon)
+	ReasonCAExpired         = ConditionReason(v1beta1.ReasonCAExpired)
+	ReasonTrustBundleFailed = ConditionReason(v1beta1.ReasonTrustBundleFailed)
+
+	ReasonIssued              = ConditionReason(v1beta1.ReasonIssued)
+	ReasonDenied              = ConditionReason(v1beta1.ReasonDenied)
+	ReasonIssuerNotFound      = ConditionReason(v1beta1.ReasonIssuerNotFound)
+	ReasonIssuerNotReady      = ConditionReason(v1beta1.ReasonIssuerNotReady)
+	ReasonProvisionerNotFound = ConditionReason(v1beta1.ReasonProvisionerNotFound)
+	ReasonCertificatePending  = ConditionReason(v1beta1.ReasonCertificatePending)
+	ReasonSigningRetrying     = ConditionReason(v1beta1.ReasonSigningRetrying)
+	ReasonSigningFailed       = ConditionReason(v1beta1.ReasonSigningFailed)
+
+	ReasonRevoked          = ConditionReason(v1beta1.ReasonRevoked)
+	ReasonRevocationFailed = ConditionReason(v1beta1.ReasonRevocationFailed)
+)
+
 // AWSCMIssuerCondition contains condition information for the issuer.
 type AWSPCAIssuerCondition struct {
-	// Type of the condition, currently ('Ready').
+	// Type of the condition, one of ('Ready', 'CredentialsValid',
+	// 'CAReachable', 'CAActive', 'CAExpiringSoon', 'PolicyValid').
 	Type ConditionType `json:"type"`
 
 	// Status of the condition, one of ('True', 'False', 'Unknown').
@@ -136,10 +227,16 @@ type AWSPCAIssuerCondition struct {
 	// Reason is a brief machine readable explanation for the condition's last
 	// transition.
 	// +optional
-	Reason string `json:"reason,omitempty"`
+	Reason ConditionReason `json:"reason,omitempty"`
 
 	// Message is a human readable description of the details of the last
 	// transition, complementing reason.
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the generation of the issuer the condition was
+	// set for. The condition may be outdated if it is older than
+	// metadata.generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
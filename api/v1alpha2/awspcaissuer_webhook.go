@@ -0,0 +1,100 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/awspca-issuer/api/v1beta1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks
+// for v1alpha2 AWSPCAIssuer resources with the manager. The webhooks convert
+// the resources to v1beta1 and apply the defaulting and validation of that
+// version.
+func (r *AWSPCAIssuer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-certmanager-awspca-v1alpha2-awspcaissuer",
+		&webhook.Admission{Handler: &AWSPCAIssuerValidator{Client: mgr.GetClient()}})
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-certmanager-awspca-v1alpha2-awspcaissuer,mutating=true,failurePolicy=fail,groups=certmanager.awspca,resources=awspcaissuers,verbs=create;update,versions=v1alpha2,name=mawspcaissuer.v1alpha2.certmanager.awspca
+
+var _ webhook.Defaulter = &AWSPCAIssuer{}
+
+// Default implements webhook.Defaulter.
+func (r *AWSPCAIssuer) Default() {
+	hub := &v1beta1.AWSPCAIssuer{}
+	if err := r.ConvertTo(hub); err != nil {
+		return
+	}
+	hub.Default()
+	_ = r.ConvertFrom(hub)
+}
+
+// +kubebuilder:webhook:path=/validate-certmanager-awspca-v1alpha2-awspcaissuer,mutating=false,failurePolicy=fail,groups=certmanager.awspca,resources=awspcaissuers,verbs=create;update,versions=v1alpha2,name=vawspcaissuer.v1alpha2.certmanager.awspca
+
+// AWSPCAIssuerValidator is the validating webhook of v1alpha2 AWSPCAIssuer
+// resources. It reads the AWS credentials secret referenced by an issuer
+// with its client, if set.
+// +kubebuilder:object:generate=false
+type AWSPCAIssuerValidator struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &AWSPCAIssuerValidator{}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *AWSPCAIssuerValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *AWSPCAIssuerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	hubValidator := &v1beta1.AWSPCAIssuerValidator{Client: v.Client}
+	iss, hub := &AWSPCAIssuer{}, &v1beta1.AWSPCAIssuer{}
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+	if err := v.decoder.DecodeRaw(req.Object, iss); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := iss.ConvertTo(hub); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1beta1.Create {
+		return v1beta1.ValidationResponse(hubValidator.ValidateCreate(ctx, hub))
+	}
+
+	oldIss, oldHub := &AWSPCAIssuer{}, &v1beta1.AWSPCAIssuer{}
+	if err := v.decoder.DecodeRaw(req.OldObject, oldIss); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := oldIss.ConvertTo(oldHub); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	return v1beta1.ValidationResponse(hubValidator.ValidateUpdate(ctx, hub, oldHub))
+}
@@ -20,7 +20,8 @@ limitations under the License.
 package v1alpha2
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -28,7 +29,7 @@ func (in *AWSPCAIssuer) DeepCopyInto(out *AWSPCAIssuer) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -105,6 +106,11 @@ func (in *AWSPCAIssuerList) DeepCopyObject() runtime.Object {
 func (in *AWSPCAIssuerSpec) DeepCopyInto(out *AWSPCAIssuerSpec) {
 	*out = *in
 	out.Provisioner = in.Provisioner
+	if in.CAExpiryWarningThreshold != nil {
+		in, out := &in.CAExpiryWarningThreshold, &out.CAExpiryWarningThreshold
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSPCAIssuerSpec.
@@ -0,0 +1,105 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&AWSPCAAuditRecord{}, &AWSPCAAuditRecordList{})
+}
+
+// AuditOutcome is the outcome of a sign attempt.
+// +kubebuilder:validation:Enum=Issued;Failed
+type AuditOutcome string
+
+const (
+	// AuditOutcomeIssued is the outcome of a sign attempt that issued a
+	// certificate.
+	AuditOutcomeIssued AuditOutcome = "Issued"
+	// AuditOutcomeFailed is the outcome of a sign attempt that failed.
+	AuditOutcomeFailed AuditOutcome = "Failed"
+)
+
+// AWSPCAAuditRecordSpec is the audit record of a sign attempt.
+type AWSPCAAuditRecordSpec struct {
+	// Time of the sign attempt.
+	Time metav1.Time `json:"time"`
+
+	// Namespace and Name of the CertificateRequest.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// UID of the CertificateRequest.
+	UID string `json:"uid"`
+	// CertificateName is the name of the Certificate that created the
+	// CertificateRequest, if any.
+	// +optional
+	CertificateName string `json:"certificateName,omitempty"`
+
+	// Issuer is the namespace/name of the AWSPCAIssuer.
+	Issuer string `json:"issuer"`
+	// CAArn is the ARN of the private CA that issued the certificate.
+	// +optional
+	CAArn string `json:"caArn,omitempty"`
+	// CertificateArn is the ARN of the issued certificate.
+	// +optional
+	CertificateArn string `json:"certificateArn,omitempty"`
+	// SerialNumber of the issued certificate, in hexadecimal.
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// SANs of the issued certificate, or of the CSR if the attempt failed.
+	// +optional
+	SANs []string `json:"sans,omitempty"`
+	// NotBefore and NotAfter are the validity of the issued certificate.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// +optional
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+
+	// Outcome of the sign attempt.
+	Outcome AuditOutcome `json:"outcome"`
+	// Error is the reason of a failed sign attempt.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Request",type=string,JSONPath=`.spec.name`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.namespace`
+// +kubebuilder:printcolumn:name="Outcome",type=string,JSONPath=`.spec.outcome`
+// +kubebuilder:printcolumn:name="Serial",type=string,JSONPath=`.spec.serialNumber`,priority=1
+// +kubebuilder:printcolumn:name="Time",type=date,JSONPath=`.spec.time`
+
+// AWSPCAAuditRecord is the audit record of a sign attempt of an AWSPCAIssuer.
+// The records are cluster scoped, so that they can not be deleted by the
+// users allowed to request certificates in a namespace.
+type AWSPCAAuditRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AWSPCAAuditRecordSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AWSPCAAuditRecordList contains a list of AWSPCAAuditRecord
+type AWSPCAAuditRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSPCAAuditRecord `json:"items"`
+}
@@ -0,0 +1,154 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&AWSPCAIssuedCertificate{}, &AWSPCAIssuedCertificateList{})
+}
+
+const (
+	// IssuerLabel is set on AWSPCAIssuedCertificates to the name of the
+	// AWSPCAIssuer that issued the certificate.
+	IssuerLabel = "certmanager.awspca/issuer"
+	// CertificateNameLabel is set on AWSPCAIssuedCertificates to the name of
+	// the Certificate the certificate was issued for, if any.
+	CertificateNameLabel = "cert-manager.io/certificate-name"
+)
+
+// AWSPCAIssuedCertificateSpec describes a certificate issued by an
+// AWSPCAIssuer.
+type AWSPCAIssuedCertificateSpec struct {
+	// IssuerName is the name of the AWSPCAIssuer that issued the certificate,
+	// in the same namespace.
+	IssuerName string `json:"issuerName"`
+	// CertificateName is the name of the Certificate the certificate was
+	// issued for, if any.
+	// +optional
+	CertificateName string `json:"certificateName,omitempty"`
+	// CertificateRequestName is the name of the CertificateRequest that was
+	// signed.
+	CertificateRequestName string `json:"certificateRequestName"`
+
+	// CertificateArn is the ARN of the certificate in AWS Private CA.
+	CertificateArn string `json:"certificateArn"`
+	// CAArn is the ARN of the private CA that issued the certificate.
+	CAArn string `json:"caArn"`
+	// SerialNumber of the certificate, in hexadecimal.
+	SerialNumber string `json:"serialNumber"`
+	// SANs of the certificate.
+	// +optional
+	SANs []string `json:"sans,omitempty"`
+	// NotBefore and NotAfter are the validity of the certificate.
+	NotBefore metav1.Time `json:"notBefore"`
+	NotAfter  metav1.Time `json:"notAfter"`
+
+	// Revocation requests the revocation of the certificate. A revoked
+	// certificate stays revoked if it is removed.
+	// +optional
+	Revocation *Revocation `json:"revocation,omitempty"`
+}
+
+// Revocation requests the revocation of a certificate.
+type Revocation struct {
+	// Reason of the revocation, UNSPECIFIED if not set.
+	// +optional
+	Reason RevocationReason `json:"reason,omitempty"`
+}
+
+// RevocationReason is the reason of the revocation of a certificate, as
+// defined by AWS Private CA.
+// +kubebuilder:validation:Enum=UNSPECIFIED;KEY_COMPROMISE;CERTIFICATE_AUTHORITY_COMPROMISE;AFFILIATION_CHANGED;SUPERSEDED;CESSATION_OF_OPERATION;PRIVILEGE_WITHDRAWN;A_A_COMPROMISE
+type RevocationReason string
+
+// RevocationReasonUnspecified is the default revocation reason.
+const RevocationReasonUnspecified RevocationReason = "UNSPECIFIED"
+
+// IssuedCertificateState is the state of an issued certificate.
+// +kubebuilder:validation:Enum=Valid;Expired;Revoked
+type IssuedCertificateState string
+
+const (
+	// IssuedCertificateStateValid is the state of a certificate that has
+	// not expired and has not been revoked.
+	IssuedCertificateStateValid IssuedCertificateState = "Valid"
+	// IssuedCertificateStateExpired is the state of a certificate past its
+	// NotAfter time.
+	IssuedCertificateStateExpired IssuedCertificateState = "Expired"
+	// IssuedCertificateStateRevoked is the state of a certificate revoked in
+	// AWS Private CA.
+	IssuedCertificateStateRevoked IssuedCertificateState = "Revoked"
+)
+
+// AWSPCAIssuedCertificateStatus defines the observed state of an issued
+// certificate.
+type AWSPCAIssuedCertificateStatus struct {
+	// State of the certificate.
+	// +optional
+	State IssuedCertificateState `json:"state,omitempty"`
+
+	// RevocationTime is the time the certificate was revoked.
+	// +optional
+	RevocationTime *metav1.Time `json:"revocationTime,omitempty"`
+
+	// Message is the error of the last revocation attempt, if it failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// CAArn is the ARN of the private CA that issued the certificate,
+	// recorded by the controller. It is used to revoke the certificate
+	// instead of the spec, which can be written by the users allowed to
+	// revoke certificates.
+	// +optional
+	CAArn string `json:"caArn,omitempty"`
+	// SerialNumber of the certificate, in hexadecimal, recorded by the
+	// controller. It is used to revoke the certificate.
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.spec.issuerName`
+// +kubebuilder:printcolumn:name="Certificate",type=string,JSONPath=`.spec.certificateName`
+// +kubebuilder:printcolumn:name="Serial",type=string,JSONPath=`.spec.serialNumber`
+// +kubebuilder:printcolumn:name="Not After",type=date,JSONPath=`.spec.notAfter`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="SANs",type=string,JSONPath=`.spec.sans`,priority=1
+
+// AWSPCAIssuedCertificate is a certificate issued by an AWSPCAIssuer. It is
+// created for every issued certificate and kept after the CertificateRequest
+// is deleted. Setting spec.revocation revokes the certificate, the other
+// fields of the spec cannot be changed.
+type AWSPCAIssuedCertificate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AWSPCAIssuedCertificateSpec   `json:"spec,omitempty"`
+	Status AWSPCAIssuedCertificateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AWSPCAIssuedCertificateList contains a list of AWSPCAIssuedCertificate
+type AWSPCAIssuedCertificateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSPCAIssuedCertificate `json:"items"`
+}
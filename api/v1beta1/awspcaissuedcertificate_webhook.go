@@ -0,0 +1,69 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the validating webhook for
+// AWSPCAIssuedCertificate resources with the manager.
+func (r *AWSPCAIssuedCertificate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-certmanager-awspca-v1beta1-awspcaissuedcertificate,mutating=false,failurePolicy=fail,groups=certmanager.awspca,resources=awspcaissuedcertificates,verbs=update,versions=v1beta1,name=vawspcaissuedcertificate.v1beta1.certmanager.awspca
+
+var _ webhook.Validator = &AWSPCAIssuedCertificate{}
+
+// ValidateCreate implements webhook.Validator. The certificates are only
+// revoked with the private CA and serial number recorded in the status by
+// the controller, so any spec is accepted.
+func (r *AWSPCAIssuedCertificate) ValidateCreate() error {
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator. Only spec.revocation can be
+// changed, the other fields of the spec describe the issued certificate.
+func (r *AWSPCAIssuedCertificate) ValidateUpdate(old runtime.Object) error {
+	oldCert, ok := old.(*AWSPCAIssuedCertificate)
+	if !ok {
+		return fmt.Errorf("expected an AWSPCAIssuedCertificate but got a %T", old)
+	}
+
+	spec, oldSpec := r.Spec.DeepCopy(), oldCert.Spec.DeepCopy()
+	spec.Revocation, oldSpec.Revocation = nil, nil
+	if apiequality.Semantic.DeepEqual(spec, oldSpec) {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AWSPCAIssuedCertificate").GroupKind(), r.Name, field.ErrorList{
+		field.Forbidden(field.NewPath("spec"), "only spec.revocation can be changed"),
+	})
+}
+
+// ValidateDelete implements webhook.Validator.
+func (r *AWSPCAIssuedCertificate) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAWSPCAIssuedCertificateValidateUpdate(t *testing.T) {
+	notAfter := metav1.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	old := &AWSPCAIssuedCertificate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "9a1e3c3a"},
+		Spec: AWSPCAIssuedCertificateSpec{
+			IssuerName:     "issuer",
+			CertificateArn: testArn + "/certificate/9a1e3c3a",
+			CAArn:          testArn,
+			SerialNumber:   "9a1e3c3a",
+			NotAfter:       notAfter,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		update  func(*AWSPCAIssuedCertificate)
+		wantErr bool
+	}{
+		{"no change", func(ic *AWSPCAIssuedCertificate) {}, false},
+		{"revocation", func(ic *AWSPCAIssuedCertificate) {
+			ic.Spec.Revocation = &Revocation{Reason: "KEY_COMPROMISE"}
+		}, false},
+		{"labels", func(ic *AWSPCAIssuedCertificate) {
+			ic.Labels = map[string]string{IssuerLabel: "other"}
+		}, false},
+		{"CA", func(ic *AWSPCAIssuedCertificate) { ic.Spec.CAArn = testSecondaryArn }, true},
+		{"serial number", func(ic *AWSPCAIssuedCertificate) { ic.Spec.SerialNumber = "01" }, true},
+		{"issuer", func(ic *AWSPCAIssuedCertificate) { ic.Spec.IssuerName = "other" }, true},
+		{"revocation and CA", func(ic *AWSPCAIssuedCertificate) {
+			ic.Spec.Revocation = &Revocation{}
+			ic.Spec.CAArn = testSecondaryArn
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := old.DeepCopy()
+			tt.update(ic)
+			if err := ic.ValidateUpdate(old); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,579 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&AWSPCAIssuer{}, &AWSPCAIssuerList{})
+}
+
+// AWSPCAIssuerSpec defines the desired state of AWSPCAIssuer
+type AWSPCAIssuerSpec struct {
+	// Arn is the ARN of the AWS Private CA. If not set, the ARN is read from
+	// the secret referenced by SecretRef.
+	// +optional
+	Arn string `json:"arn,omitempty"`
+
+	// Region is the AWS region of the private CA. If not set, the region is
+	// read from the secret referenced by SecretRef, or derived from the ARN.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CertificateAuthorities is an ordered list of private CAs used instead
+	// of Arn and Region. Certificates are issued by the first active CA, or
+	// by an active CA chosen by weight if weights are set; the other active
+	// CAs are tried in order when a CA is unavailable, e.g. during a
+	// regional outage or when the CA is disabled.
+	// +optional
+	CertificateAuthorities []CertificateAuthority `json:"certificateAuthorities,omitempty"`
+
+	// SecretRef references the secret holding the AWS credentials. If not
+	// set, the default AWS credential chain of the controller is used, e.g.
+	// environment variables, a web identity token or an instance role. This
+	// requires the controller to run with --allow-ambient-credentials.
+	// +optional
+	SecretRef *AWSCredentialsSecretReference `json:"secretRef,omitempty"`
+
+	// CAExpiryWarningThreshold is the remaining validity of the private CA
+	// certificate below which the CAExpiringSoon condition is set to True.
+	// Defaults to the --ca-expiry-warning-threshold flag of the controller.
+	// +optional
+	CAExpiryWarningThreshold *metav1.Duration `json:"caExpiryWarningThreshold,omitempty"`
+
+	// TemplateArn is the ARN of the AWS Private CA certificate template used
+	// to issue certificates. Defaults to EndEntityCertificate/V1. Use a
+	// passthrough template, e.g.
+	// arn:aws:acm-pca:::template/EndEntityCertificate_CSRPassthrough/V1, to
+	// include the email and URI SANs of the certificate requests in the
+	// issued certificates.
+	// +optional
+	TemplateArn string `json:"templateArn,omitempty"`
+
+	// Subject configures the subject of the issued certificates. It requires
+	// an API passthrough template in TemplateArn, e.g.
+	// arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1.
+	// With such a template and no Subject, the common name of the
+	// certificate request or its first SAN is used.
+	// +optional
+	Subject *SubjectTemplate `json:"subject,omitempty"`
+
+	// CSRPolicy configures the checks of the certificate requests. The checks
+	// always run, with the defaults if not set, and every violation is
+	// reported in the Failed condition of the CertificateRequest.
+	// +optional
+	CSRPolicy *CSRPolicy `json:"csrPolicy,omitempty"`
+
+	// SPIFFE configures the validation of the SPIFFE IDs in the URI SANs of
+	// the certificate requests. SPIFFE IDs are always checked to be well
+	// formed, and a request may contain at most one.
+	// +optional
+	SPIFFE *SPIFFEConfig `json:"spiffe,omitempty"`
+
+	// TrustBundle distributes the CA bundle of the issuer to ConfigMaps, so
+	// that applications can trust the certificates it issues.
+	// +optional
+	TrustBundle *TrustBundle `json:"trustBundle,omitempty"`
+
+	// RetryPolicy configures the retries of the failed AWS Private CA calls
+	// and the wait for the certificates to be issued.
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+// AWSPCAIssuerStatus defines the observed state of AWSPCAIssuer
+type AWSPCAIssuerStatus struct {
+	// +optional
+	Conditions []AWSPCAIssuerCondition `json:"conditions,omitempty"`
+
+	// CertificateAuthorities reports the health of each private CA of the
+	// issuer, in the order they are tried.
+	// +optional
+	CertificateAuthorities []CertificateAuthorityStatus `json:"certificateAuthorities,omitempty"`
+
+	// RetiredCertificateAuthorities are the ARNs of the private CAs removed
+	// from the issuer. The certificates they issued can still be revoked.
+	// +optional
+	RetiredCertificateAuthorities []string `json:"retiredCertificateAuthorities,omitempty"`
+
+	// CABundle contains the PEM encoded certificates and chains of all the
+	// private CAs of the issuer, including the draining ones. It is set as
+	// the CA of the CertificateRequests signed by the issuer.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// AWSPCAIssuer is the Schema for the AWSPCAissuers API
+// +kubebuilder:subresource:status
+type AWSPCAIssuer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AWSPCAIssuerSpec   `json:"spec,omitempty"`
+	Status AWSPCAIssuerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AWSPCAIssuerList contains a list of AWSPCAIssuer
+type AWSPCAIssuerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSPCAIssuer `json:"items"`
+}
+
+// SecretKeySelector contains the reference to a secret.
+type SecretKeySelector struct {
+	// The key of the secret to select from. Must be a valid secret key.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// AWSCredentialsSecretReference references the secret holding the AWS
+// credentials and, optionally, the region and the ARN of the private CA.
+type AWSCredentialsSecretReference struct {
+	// The name of the secret in the issuer namespace.
+	Name string `json:"name"`
+
+	// Reference to AWS access key, the key defaults to 'accesskey'.
+	// +optional
+	AccessKeyRef SecretKeySelector `json:"accesskeyRef,omitempty"`
+
+	// Reference to AWS secret key, the key defaults to 'secretkey'.
+	// +optional
+	SecretKeyRef SecretKeySelector `json:"secretkeyRef,omitempty"`
+
+	// Reference to AWS region, used if spec.region is not set. The key
+	// defaults to 'region' if neither spec.region nor spec.arn are set.
+	// +optional
+	RegionRef SecretKeySelector `json:"regionRef,omitempty"`
+
+	// Reference to private CA ARN, used if spec.arn is not set. The key
+	// defaults to 'arn' if spec.arn is not set.
+	// +optional
+	ArnRef SecretKeySelector `json:"arnRef,omitempty"`
+}
+
+// CertificateAuthority references an AWS Private CA.
+type CertificateAuthority struct {
+	// Arn is the ARN of the AWS Private CA.
+	Arn string `json:"arn"`
+
+	// Region is the AWS region of the private CA, it defaults to the region
+	// of the ARN.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// State of the private CA, one of ('Active', 'Draining'). Draining CAs
+	// no longer issue certificates but stay in the CA bundle of the issuer,
+	// so that certificates issued by them are still trusted while a CA is
+	// rotated. Defaults to Active.
+	// +optional
+	State CertificateAuthorityState `json:"state,omitempty"`
+
+	// Weight is the relative share of new certificates issued by this CA
+	// among the active CAs. If no active CA has a weight, the first active
+	// CA issues all the certificates. CAs without a weight are then only
+	// used on failover.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// CertificateAuthorityState represents the state of a private CA of an
+// issuer.
+// +kubebuilder:validation:Enum=Active;Draining
+type CertificateAuthorityState string
+
+const (
+	// CertificateAuthorityActive means the private CA issues certificates.
+	CertificateAuthorityActive CertificateAuthorityState = "Active"
+
+	// CertificateAuthorityDraining means the private CA no longer issues
+	// certificates but is still trusted.
+	CertificateAuthorityDraining CertificateAuthorityState = "Draining"
+)
+
+// CertificateAuthorityStatus contains the observed state of a private CA.
+type CertificateAuthorityStatus struct {
+	// Arn is the ARN of the AWS Private CA.
+	Arn string `json:"arn"`
+
+	// Ready is True if the private CA is active and can issue certificates,
+	// one of ('True', 'False', 'Unknown').
+	Ready ConditionStatus `json:"ready"`
+
+	// Reason is a brief machine readable explanation for the readiness of
+	// the private CA.
+	// +optional
+	Reason ConditionReason `json:"reason,omitempty"`
+
+	// Message is a human readable description of the readiness of the
+	// private CA.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// NotAfter is the expiry time of the private CA certificate.
+	// +optional
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+}
+
+// TrustBundle configures the ConfigMaps the CA bundle of an issuer is
+// written to. They are kept up to date when the CAs of the issuer change, and
+// deleted with the issuer.
+type TrustBundle struct {
+	// ConfigMapName is the name of the ConfigMaps, it defaults to
+	// '<issuer name>-ca-bundle'.
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// Key is the key of the CA bundle in the ConfigMaps, it defaults to
+	// 'ca.crt'.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Namespaces lists the namespaces the ConfigMap is written to. The
+	// namespaces other than the one of the issuer must have the
+	// 'certmanager.awspca/trust-bundles: "true"' label.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects additional namespaces the ConfigMap is
+	// written to, among the ones with the
+	// 'certmanager.awspca/trust-bundles: "true"' label. If neither
+	// Namespaces nor NamespaceSelector are set, the ConfigMap is only
+	// written to the namespace of the issuer.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// RetryPolicy configures how the AWS Private CA calls of an issuer are
+// retried, and how long it waits for a certificate to be issued.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries of a failed AWS Private
+	// CA call, e.g. when throttled. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// MinRetryDelay is the delay before the first retry of a failed call.
+	// It doubles with every retry, with a random jitter, up to
+	// MaxRetryDelay. Defaults to 30ms, and to 500ms for throttled calls.
+	// +optional
+	MinRetryDelay *metav1.Duration `json:"minRetryDelay,omitempty"`
+
+	// MaxRetryDelay is the maximum delay between two retries of a failed
+	// call. Defaults to 5m.
+	// +optional
+	MaxRetryDelay *metav1.Duration `json:"maxRetryDelay,omitempty"`
+
+	// WaitInterval is the delay between two checks of a certificate being
+	// issued, with a random jitter of up to 20%. Defaults to 3s.
+	// +optional
+	WaitInterval *metav1.Duration `json:"waitInterval,omitempty"`
+
+	// WaitMaxAttempts is the maximum number of checks of a certificate
+	// being issued. The CertificateRequest is then marked as Pending and
+	// checked again later. Defaults to 60.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WaitMaxAttempts *int32 `json:"waitMaxAttempts,omitempty"`
+}
+
+// SubjectTemplate configures the subject of the issued certificates.
+type SubjectTemplate struct {
+	// Organization of the subject.
+	// +optional
+	Organization string `json:"organization,omitempty"`
+
+	// OrganizationalUnit of the subject.
+	// +optional
+	OrganizationalUnit string `json:"organizationalUnit,omitempty"`
+
+	// Country of the subject, a two-letter ISO 3166 code.
+	// +optional
+	Country string `json:"country,omitempty"`
+
+	// CommonName is a Go template of the common name, executed with the
+	// .Namespace and .Name of the CertificateRequest, the .CertificateName
+	// of its Certificate, and the .CommonName, .SAN (first SAN that is not
+	// localhost), .DNSNames, .IPAddresses, .EmailAddresses and .URIs of its
+	// CSR, e.g. '{{ .CertificateName }}.{{ .Namespace }}'. Defaults to the
+	// common name of the CSR, or its first SAN.
+	// +optional
+	CommonName string `json:"commonName,omitempty"`
+}
+
+// CSRPolicy configures the checks of the certificate requests. Requests
+// signed with a weak hash function such as SHA-1, with duplicate SANs, or
+// with invalid wildcard DNS names are always rejected.
+type CSRPolicy struct {
+	// MinRSAKeySize is the minimum size in bits of RSA keys. Defaults to
+	// 2048.
+	// +kubebuilder:validation:Minimum=2048
+	// +optional
+	MinRSAKeySize int32 `json:"minRSAKeySize,omitempty"`
+
+	// ECDSACurves are the allowed curves of ECDSA keys, among ('P-256',
+	// 'P-384', 'P-521'). Defaults to all of them.
+	// +optional
+	ECDSACurves []string `json:"ecdsaCurves,omitempty"`
+
+	// MaxSANs is the maximum number of SANs of a request. Unlimited if not
+	// set.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxSANs int32 `json:"maxSANs,omitempty"`
+
+	// ForbidWildcards rejects the requests with wildcard DNS names.
+	// +optional
+	ForbidWildcards bool `json:"forbidWildcards,omitempty"`
+
+	// AllowCommonNameNotInSANs accepts the requests whose common name is
+	// not one of their SANs.
+	// +optional
+	AllowCommonNameNotInSANs bool `json:"allowCommonNameNotInSANs,omitempty"`
+}
+
+// SPIFFEConfig configures the validation of SPIFFE IDs.
+type SPIFFEConfig struct {
+	// TrustDomain is the trust domain the SPIFFE IDs must belong to, e.g.
+	// 'example.org'.
+	TrustDomain string `json:"trustDomain"`
+}
+
+// DefaultTrustBundleKey is the default key of the CA bundle in the trust
+// bundle ConfigMaps.
+const DefaultTrustBundleKey = "ca.crt"
+
+// TrustBundleFinalizer is set on the issuers distributing their CA bundle,
+// to delete the ConfigMaps when the issuer is deleted.
+const TrustBundleFinalizer = "certmanager.awspca/trust-bundle"
+
+// CertificateAuthorityArnAnnotation is set on the CertificateRequests signed
+// by an AWSPCAIssuer to the ARN of the private CA that issued the
+// certificate.
+const CertificateAuthorityArnAnnotation = "certmanager.awspca/certificate-authority-arn"
+
+// CertificateArnAnnotation is set on the CertificateRequests to the ARN of
+// their certificate when it is still being issued by AWS Private CA, so that
+// it is retrieved instead of issued again.
+const CertificateArnAnnotation = "certmanager.awspca/certificate-arn"
+
+// ConditionType represents a AWSPCAIssuer condition type.
+// +kubebuilder:validation:Enum=Ready;CredentialsValid;CAReachable;CAActive;CAExpiringSoon;PolicyValid
+type ConditionType string
+
+const (
+	// ConditionReady indicates that a AWSPCAIssuer is ready for use. It is
+	// the conjunction of the other conditions: it is only True if
+	// CredentialsValid, CAReachable, CAActive and PolicyValid are True and
+	// the private CA certificate has not expired.
+	ConditionReady ConditionType = "Ready"
+
+	// ConditionCredentialsValid indicates that the AWS credentials of a
+	// AWSPCAIssuer could be loaded and are accepted by AWS.
+	ConditionCredentialsValid ConditionType = "CredentialsValid"
+
+	// ConditionCAReachable indicates that at least one of the private CAs
+	// of a AWSPCAIssuer could be described.
+	ConditionCAReachable ConditionType = "CAReachable"
+
+	// ConditionCAActive indicates that at least one of the private CAs of a
+	// AWSPCAIssuer that is not draining is active.
+	ConditionCAActive ConditionType = "CAActive"
+
+	// ConditionCAExpiringSoon indicates that the certificate of the private
+	// CA used by a AWSPCAIssuer is close to its expiry. Certificates issued
+	// by the CA cannot outlive it, so their validity shortens as it nears.
+	ConditionCAExpiringSoon ConditionType = "CAExpiringSoon"
+
+	// ConditionPolicyValid indicates that the spec of a AWSPCAIssuer is
+	// valid.
+	ConditionPolicyValid ConditionType = "PolicyValid"
+)
+
+// ConditionStatus represents a condition's status.
+// +kubebuilder:validation:Enum=True;False;Unknown
+type ConditionStatus string
+
+// These are valid condition statuses. "ConditionTrue" means a resource is in
+// the condition; "ConditionFalse" means a resource is not in the condition;
+// "ConditionUnknown" means kubernetes can't decide if a resource is in the
+// condition or not.
+const (
+	// ConditionTrue represents the fact that a given condition is true
+	ConditionTrue ConditionStatus = "True"
+
+	// ConditionFalse represents the fact that a given condition is false
+	ConditionFalse ConditionStatus = "False"
+
+	// ConditionUnknown represents the fact that a given condition is unknown
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// ConditionReason is a brief machine readable explanation for the status of
+// a condition, used as the reason of the Events fired with the change.
+type ConditionReason string
+
+// The reasons of the Ready, PolicyValid and CredentialsValid conditions of
+// an AWSPCAIssuer. When Ready is False, its reason is the reason of the
+// condition that failed.
+const (
+	// ReasonVerified means that the issuer is ready to sign certificates.
+	ReasonVerified ConditionReason = "Verified"
+	// ReasonValidation means that the spec of the issuer is invalid.
+	ReasonValidation ConditionReason = "Validation"
+	// ReasonNotFound means that the secret of the issuer, or one of its
+	// keys, does not exist.
+	ReasonNotFound ConditionReason = "NotFound"
+	// ReasonSecretError means that the secret of the issuer could not be
+	// retrieved.
+	ReasonSecretError ConditionReason = "SecretError"
+	// ReasonError means that the provisioner of the issuer could not be
+	// initialized for another reason.
+	ReasonError ConditionReason = "Error"
+)
+
+// The reasons of the readiness of a private CA and of the CAExpiringSoon
+// condition of an AWSPCAIssuer.
+const (
+	// ReasonActive means that the private CA is active.
+	ReasonActive ConditionReason = "Active"
+	// ReasonNotActive means that the private CA is not active, e.g. disabled
+	// or deleted.
+	ReasonNotActive ConditionReason = "NotActive"
+	// ReasonCheckFailed means that the private CA could not be described.
+	ReasonCheckFailed ConditionReason = "CheckFailed"
+	// ReasonValid means that the private CA certificate is not close to its
+	// expiry, or that the spec of the issuer is valid.
+	ReasonValid ConditionReason = "Valid"
+	// ReasonExpiringSoon means that the private CA certificate expires
+	// within the warning threshold of the issuer.
+	ReasonExpiringSoon ConditionReason = "ExpiringSoon"
+	// ReasonExpired means that the private CA certificate has expired.
+	ReasonExpired ConditionReason = "Expired"
+)
+
+// The reasons of the CredentialsValid and CAReachable conditions of an
+// AWSPCAIssuer.
+const (
+	// ReasonAccepted means that the AWS credentials of the issuer were
+	// accepted by AWS.
+	ReasonAccepted ConditionReason = "Accepted"
+	// ReasonRejected means that the AWS credentials of the issuer were
+	// rejected by AWS, e.g. because the access key was revoked.
+	ReasonRejected ConditionReason = "Rejected"
+	// ReasonReachable means that at least one of the private CAs of the
+	// issuer could be described.
+	ReasonReachable ConditionReason = "Reachable"
+	// ReasonUnreachable means that none of the private CAs of the issuer
+	// could be described.
+	ReasonUnreachable ConditionReason = "Unreachable"
+	// ReasonNotChecked means that the condition could not be checked
+	// because an earlier check failed.
+	ReasonNotChecked ConditionReason = "NotChecked"
+)
+
+// The reasons of the Events of an AWSPCAIssuer that do not change a
+// condition.
+const (
+	// ReasonCAExpiringSoon is the reason of the Warning Events fired while
+	// the private CA certificate is close to its expiry.
+	ReasonCAExpiringSoon ConditionReason = "CAExpiringSoon"
+	// ReasonCAExpired is the reason of the Warning Events fired once the
+	// private CA certificate has expired.
+	ReasonCAExpired ConditionReason = "CAExpired"
+	// ReasonTrustBundleFailed means that the trust bundle of the issuer
+	// could not be distributed.
+	ReasonTrustBundleFailed ConditionReason = "TrustBundleFailed"
+)
+
+// The reasons of the Events of a CertificateRequest. The Ready condition of
+// a CertificateRequest keeps the reasons of cert-manager, which cert-manager
+// relies on: Pending, Failed, Issued or Denied.
+const (
+	// ReasonIssued means that the certificate was issued.
+	ReasonIssued ConditionReason = "Issued"
+	// ReasonDenied means that the request was denied by an approver. The
+	// request is failed.
+	ReasonDenied ConditionReason = "Denied"
+	// ReasonIssuerNotFound means that the issuer of the request does not
+	// exist or could not be retrieved.
+	ReasonIssuerNotFound ConditionReason = "IssuerNotFound"
+	// ReasonIssuerNotReady means that the issuer of the request is not
+	// ready.
+	ReasonIssuerNotReady ConditionReason = "IssuerNotReady"
+	// ReasonProvisionerNotFound means that the issuer of the request has
+	// not been loaded by the controller yet.
+	ReasonProvisionerNotFound ConditionReason = "ProvisionerNotFound"
+	// ReasonCertificatePending means that the certificate is still being
+	// issued by AWS Private CA.
+	ReasonCertificatePending ConditionReason = "CertificatePending"
+	// ReasonSigningRetrying means that the certificate could not be issued
+	// or retrieved because of a temporary failure, e.g. AWS Private CA could
+	// not be reached or throttled the request. The request is retried.
+	ReasonSigningRetrying ConditionReason = "SigningRetrying"
+	// ReasonSigningFailed means that AWS Private CA did not issue the
+	// certificate. The request is failed.
+	ReasonSigningFailed ConditionReason = "SigningFailed"
+)
+
+// The reasons of the Events of an AWSPCAIssuedCertificate.
+const (
+	// ReasonRevoked means that the certificate was revoked.
+	ReasonRevoked ConditionReason = "Revoked"
+	// ReasonRevocationFailed means that the certificate could not be
+	// revoked.
+	ReasonRevocationFailed ConditionReason = "RevocationFailed"
+)
+
+// AWSPCAIssuerCondition contains condition information for the issuer.
+type AWSPCAIssuerCondition struct {
+	// Type of the condition, one of ('Ready', 'CredentialsValid',
+	// 'CAReachable', 'CAActive', 'CAExpiringSoon', 'PolicyValid').
+	Type ConditionType `json:"type"`
+
+	// Status of the condition, one of ('True', 'False', 'Unknown').
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status ConditionStatus `json:"status"`
+
+	// LastTransitionTime is the timestamp corresponding to the last status
+	// change of this condition.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a brief machine readable explanation for the condition's last
+	// transition.
+	// +optional
+	Reason ConditionReason `json:"reason,omitempty"`
+
+	// Message is a human readable description of the details of the last
+	// transition, complementing reason.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the generation of the issuer the condition was
+	// set for. The condition may be outdated if it is older than
+	// metadata.generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
@@ -0,0 +1,563 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Default names of the keys of the AWS credentials secret.
+const (
+	DefaultAccessKeyKey = "accesskey"
+	DefaultSecretKeyKey = "secretkey"
+	DefaultRegionKey    = "region"
+	DefaultArnKey       = "arn"
+)
+
+// regionRegexp matches the names of the AWS regions, e.g. us-east-1,
+// cn-north-1 or us-gov-west-1.
+var regionRegexp = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]+$`)
+
+// countryRegexp matches the two-letter ISO 3166 country codes.
+var countryRegexp = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// spiffeTrustDomainRegexp matches the trust domains of SPIFFE IDs.
+var spiffeTrustDomainRegexp = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks
+// for AWSPCAIssuer resources with the manager. The validating webhook reads
+// the AWS credentials secrets with the client of the manager.
+func (r *AWSPCAIssuer) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-certmanager-awspca-v1beta1-awspcaissuer",
+		&webhook.Admission{Handler: &AWSPCAIssuerValidator{Client: mgr.GetClient()}})
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-certmanager-awspca-v1beta1-awspcaissuer,mutating=true,failurePolicy=fail,groups=certmanager.awspca,resources=awspcaissuers,verbs=create;update,versions=v1beta1,name=mawspcaissuer.v1beta1.certmanager.awspca
+
+var _ webhook.Defaulter = &AWSPCAIssuer{}
+
+// Default sets the default names of the keys of the AWS credentials secret
+// and of the trust bundle ConfigMaps. The region and ARN keys are only
+// defaulted if the values are not set in the spec and no private CAs are
+// listed.
+func (r *AWSPCAIssuer) Default() {
+	if tb := r.Spec.TrustBundle; tb != nil {
+		if tb.ConfigMapName == "" {
+			tb.ConfigMapName = r.Name + "-ca-bundle"
+		}
+		if tb.Key == "" {
+			tb.Key = DefaultTrustBundleKey
+		}
+	}
+
+	ref := r.Spec.SecretRef
+	if ref == nil {
+		return
+	}
+	if ref.AccessKeyRef.Key == "" {
+		ref.AccessKeyRef.Key = DefaultAccessKeyKey
+	}
+	if ref.SecretKeyRef.Key == "" {
+		ref.SecretKeyRef.Key = DefaultSecretKeyKey
+	}
+	// The region and ARN of the private CAs listed in the spec are never read
+	// from the secret.
+	if len(r.Spec.CertificateAuthorities) > 0 {
+		return
+	}
+	if ref.RegionRef.Key == "" && r.Spec.Region == "" && r.Spec.Arn == "" {
+		ref.RegionRef.Key = DefaultRegionKey
+	}
+	if ref.ArnRef.Key == "" && r.Spec.Arn == "" {
+		ref.ArnRef.Key = DefaultArnKey
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-certmanager-awspca-v1beta1-awspcaissuer,mutating=false,failurePolicy=fail,groups=certmanager.awspca,resources=awspcaissuers,verbs=create;update,versions=v1beta1,name=vawspcaissuer.v1beta1.certmanager.awspca
+
+// AWSPCAIssuerValidator is the validating webhook of AWSPCAIssuer resources.
+// It reads the AWS credentials secret referenced by an issuer with its
+// client, if set.
+// +kubebuilder:object:generate=false
+type AWSPCAIssuerValidator struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &AWSPCAIssuerValidator{}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *AWSPCAIssuerValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *AWSPCAIssuerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	iss, oldIss := &AWSPCAIssuer{}, &AWSPCAIssuer{}
+	var err error
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		if err := v.decoder.Decode(req, iss); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.ValidateCreate(ctx, iss)
+	case admissionv1beta1.Update:
+		if err := v.decoder.DecodeRaw(req.Object, iss); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldIss); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.ValidateUpdate(ctx, iss, oldIss)
+	}
+	return ValidationResponse(err)
+}
+
+// ValidationResponse returns the admission response of a validating webhook
+// that returned the given error, nil if the object is valid.
+func ValidationResponse(err error) admission.Response {
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// ValidateCreate validates a new issuer.
+func (v *AWSPCAIssuerValidator) ValidateCreate(ctx context.Context, iss *AWSPCAIssuer) error {
+	return v.validate(ctx, iss)
+}
+
+// ValidateUpdate validates an updated issuer. The private CA used by an
+// issuer without a list of private CAs, from spec.arn or from the AWS
+// credentials secret, cannot be changed, as the certificates already issued
+// would no longer chain to the CA of the issuer. It can only be moved to
+// another of these fields or to the list of private CAs.
+func (v *AWSPCAIssuerValidator) ValidateUpdate(ctx context.Context, iss, old *AWSPCAIssuer) error {
+	// Never prevent the controller from removing its finalizer.
+	if iss.DeletionTimestamp != nil {
+		return nil
+	}
+	if err := v.validateImmutableFields(ctx, iss, old); err != nil {
+		return err
+	}
+	return v.validate(ctx, iss)
+}
+
+// validateImmutableFields returns an error if the update changes the private
+// CA of an issuer that does not list its private CAs.
+func (v *AWSPCAIssuerValidator) validateImmutableFields(ctx context.Context, iss, old *AWSPCAIssuer) error {
+	if len(old.Spec.CertificateAuthorities) > 0 {
+		return nil
+	}
+	oldArn, _ := v.caArn(ctx, old)
+	if oldArn == "" || iss.hasCertificateAuthority(oldArn) {
+		return nil
+	}
+	newArn, fldPath := v.caArn(ctx, iss)
+	// The ARN read from a secret that does not exist (yet) is unknown.
+	if newArn == "" && len(iss.Spec.CertificateAuthorities) == 0 && iss.Spec.Arn == "" {
+		return nil
+	}
+	if newArn == oldArn {
+		return nil
+	}
+	if fldPath == nil {
+		fldPath = field.NewPath("spec", "arn")
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AWSPCAIssuer").GroupKind(), iss.Name, field.ErrorList{
+		field.Forbidden(fldPath, fmt.Sprintf("private CA %s cannot be changed", oldArn)),
+	})
+}
+
+// caArn returns the ARN of the private CA of an issuer that does not list its
+// private CAs, from spec.arn or from the AWS credentials secret, and the path
+// of the field it is read from. It returns an empty ARN if it cannot be read.
+func (v *AWSPCAIssuerValidator) caArn(ctx context.Context, iss *AWSPCAIssuer) (string, *field.Path) {
+	if len(iss.Spec.CertificateAuthorities) > 0 {
+		return "", nil
+	}
+	if iss.Spec.Arn != "" {
+		return iss.Spec.Arn, field.NewPath("spec", "arn")
+	}
+	p := iss.Spec.SecretRef
+	if p == nil || p.ArnRef.Key == "" || v.Client == nil {
+		return "", nil
+	}
+	var secret core.Secret
+	key := types.NamespacedName{Namespace: iss.Namespace, Name: p.Name}
+	if err := v.Client.Get(ctx, key, &secret); err != nil {
+		return "", nil
+	}
+	return string(secret.Data[p.ArnRef.Key]), field.NewPath("spec", "secretRef", "arnRef", "key")
+}
+
+func (v *AWSPCAIssuerValidator) validate(ctx context.Context, r *AWSPCAIssuer) error {
+	var allErrs field.ErrorList
+	hasCAs := len(r.Spec.CertificateAuthorities) > 0
+	if r.Spec.SecretRef != nil {
+		allErrs = append(allErrs, validateSecretReference(*r.Spec.SecretRef, r.Spec.Arn != "" || hasCAs, field.NewPath("spec", "secretRef"))...)
+	} else if r.Spec.Arn == "" && !hasCAs {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "arn"), "must be set if spec.secretRef and spec.certificateAuthorities are not set"))
+	}
+	if hasCAs {
+		allErrs = append(allErrs, r.validateCertificateAuthorities(field.NewPath("spec"))...)
+	}
+	if r.Spec.TemplateArn != "" {
+		if err := validateTemplateArn(r.Spec.TemplateArn); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "templateArn"), r.Spec.TemplateArn, err.Error()))
+		}
+	}
+	if r.Spec.Subject != nil {
+		allErrs = append(allErrs, validateSubject(*r.Spec.Subject, r.Spec.TemplateArn, field.NewPath("spec", "subject"))...)
+	}
+	if r.Spec.CSRPolicy != nil {
+		allErrs = append(allErrs, validateCSRPolicy(*r.Spec.CSRPolicy, field.NewPath("spec", "csrPolicy"))...)
+	}
+	if r.Spec.SPIFFE != nil && !spiffeTrustDomainRegexp.MatchString(r.Spec.SPIFFE.TrustDomain) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "spiffe", "trustDomain"), r.Spec.SPIFFE.TrustDomain,
+			"must consist of lowercase letters, digits, '.', '-' and '_'"))
+	}
+	if r.Spec.TrustBundle != nil {
+		allErrs = append(allErrs, validateTrustBundle(*r.Spec.TrustBundle, field.NewPath("spec", "trustBundle"))...)
+	}
+	if r.Spec.RetryPolicy != nil {
+		allErrs = append(allErrs, validateRetryPolicy(*r.Spec.RetryPolicy, field.NewPath("spec", "retryPolicy"))...)
+	}
+	if t := r.Spec.CAExpiryWarningThreshold; t != nil && t.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "caExpiryWarningThreshold"), t.Duration.String(), "must not be negative"))
+	}
+
+	// The region and the private CA ARN may be stored in the secret. If it
+	// already exists, check them now rather than when the issuer is
+	// reconciled.
+	if len(allErrs) == 0 && !hasCAs {
+		allErrs = append(allErrs, v.validateRegionAndArn(ctx, r)...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AWSPCAIssuer").GroupKind(), r.Name, allErrs)
+}
+
+func validateSecretReference(p AWSCredentialsSecretReference, hasArn bool, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if p.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(p.Name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), p.Name, msg))
+		}
+	}
+
+	refs := []struct {
+		name     string
+		ref      SecretKeySelector
+		required bool
+	}{
+		{"accesskeyRef", p.AccessKeyRef, true},
+		{"secretkeyRef", p.SecretKeyRef, true},
+		{"regionRef", p.RegionRef, false},
+		{"arnRef", p.ArnRef, !hasArn},
+	}
+	for _, r := range refs {
+		keyPath := fldPath.Child(r.name, "key")
+		if r.ref.Key == "" {
+			if r.required {
+				allErrs = append(allErrs, field.Required(keyPath, ""))
+			}
+			continue
+		}
+		for _, msg := range validation.IsConfigMapKey(r.ref.Key) {
+			allErrs = append(allErrs, field.Invalid(keyPath, r.ref.Key, msg))
+		}
+	}
+	return allErrs
+}
+
+// validateCertificateAuthorities checks the list of private CAs, which
+// replaces the region and ARN of the spec and of the secret.
+func (r *AWSPCAIssuer) validateCertificateAuthorities(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	exclusive := "must not be set if spec.certificateAuthorities is set"
+	if r.Spec.Arn != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("arn"), exclusive))
+	}
+	if r.Spec.Region != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("region"), exclusive))
+	}
+	if p := r.Spec.SecretRef; p != nil {
+		if p.ArnRef.Key != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("secretRef", "arnRef", "key"), exclusive))
+		}
+		if p.RegionRef.Key != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("secretRef", "regionRef", "key"), exclusive))
+		}
+	}
+
+	active := false
+	seen := make(map[string]bool)
+	for i, ca := range r.Spec.CertificateAuthorities {
+		idxPath := fldPath.Child("certificateAuthorities").Index(i)
+		switch ca.State {
+		case "", CertificateAuthorityActive:
+			active = true
+		case CertificateAuthorityDraining:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("state"), ca.State,
+				[]string{string(CertificateAuthorityActive), string(CertificateAuthorityDraining)}))
+		}
+		if ca.Weight != nil && *ca.Weight < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("weight"), *ca.Weight, "must not be negative"))
+		}
+		if ca.Arn == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("arn"), ""))
+			continue
+		}
+		if seen[ca.Arn] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("arn"), ca.Arn))
+		}
+		seen[ca.Arn] = true
+
+		parsed, err := ParseCAArn(ca.Arn)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("arn"), ca.Arn, err.Error()))
+			continue
+		}
+		if ca.Region != "" {
+			if err := ValidateRegion(ca.Region); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("region"), ca.Region, err.Error()))
+			} else if parsed.Region != ca.Region {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("arn"), ca.Arn,
+					fmt.Sprintf("private CA region %s does not match region %s", parsed.Region, ca.Region)))
+			}
+		}
+	}
+	if !active {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("certificateAuthorities"), len(r.Spec.CertificateAuthorities),
+			"at least one private CA must be active"))
+	}
+	return allErrs
+}
+
+func validateTrustBundle(tb TrustBundle, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if tb.ConfigMapName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("configMapName"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(tb.ConfigMapName) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("configMapName"), tb.ConfigMapName, msg))
+		}
+	}
+	if tb.Key == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("key"), ""))
+	} else {
+		for _, msg := range validation.IsConfigMapKey(tb.Key) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("key"), tb.Key, msg))
+		}
+	}
+	for i, ns := range tb.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaces").Index(i), ns, msg))
+		}
+	}
+	if tb.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(tb.NamespaceSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaceSelector"), tb.NamespaceSelector, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// hasCertificateAuthority returns true if the private CA with the given ARN
+// is listed in the spec.
+func (r *AWSPCAIssuer) hasCertificateAuthority(caArn string) bool {
+	for _, ca := range r.Spec.CertificateAuthorities {
+		if ca.Arn == caArn {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRegionAndArn checks the syntax of the region and of the private CA
+// ARN, and that the CA lives in that region. Values that are not set in the
+// spec are read from the AWS credentials secret; they are not checked if the
+// secret or its keys do not exist yet, the controller reports those errors on
+// the issuer status.
+func (v *AWSPCAIssuerValidator) validateRegionAndArn(ctx context.Context, r *AWSPCAIssuer) field.ErrorList {
+	fldPath := field.NewPath("spec")
+	regionPath, arnPath := fldPath.Child("region"), fldPath.Child("arn")
+	region, caArn := r.Spec.Region, r.Spec.Arn
+
+	var secret core.Secret
+	if p := r.Spec.SecretRef; p != nil && ((region == "" && p.RegionRef.Key != "") || caArn == "") {
+		key := types.NamespacedName{Namespace: r.Namespace, Name: p.Name}
+		if v.Client != nil && v.Client.Get(ctx, key, &secret) == nil {
+			if value, ok := secret.Data[p.RegionRef.Key]; region == "" && ok {
+				region = string(value)
+				regionPath = fldPath.Child("secretRef", "regionRef", "key")
+			}
+			if value, ok := secret.Data[p.ArnRef.Key]; caArn == "" && ok {
+				caArn = string(value)
+				arnPath = fldPath.Child("secretRef", "arnRef", "key")
+			}
+		}
+	}
+
+	var allErrs field.ErrorList
+	if region != "" {
+		if err := ValidateRegion(region); err != nil {
+			allErrs = append(allErrs, field.Invalid(regionPath, region, err.Error()))
+		}
+	}
+	if caArn != "" {
+		parsed, err := ParseCAArn(caArn)
+		switch {
+		case err != nil:
+			allErrs = append(allErrs, field.Invalid(arnPath, caArn, err.Error()))
+		case region != "" && parsed.Region != region:
+			allErrs = append(allErrs, field.Invalid(arnPath, caArn,
+				fmt.Sprintf("private CA region %s does not match region %s", parsed.Region, region)))
+		}
+	}
+	return allErrs
+}
+
+func validateSubject(s SubjectTemplate, templateArn string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if !strings.Contains(templateArn, "_APIPassthrough/") && !strings.Contains(templateArn, "_APICSRPassthrough/") {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "requires an API passthrough template in spec.templateArn"))
+	}
+	if s.Country != "" && !countryRegexp.MatchString(s.Country) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("country"), s.Country, "must be a two-letter ISO 3166 code"))
+	}
+	if s.CommonName != "" {
+		if _, err := template.New("commonName").Parse(s.CommonName); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("commonName"), s.CommonName, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+func validateCSRPolicy(p CSRPolicy, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if p.MinRSAKeySize != 0 && p.MinRSAKeySize < 2048 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minRSAKeySize"), p.MinRSAKeySize, "must be at least 2048"))
+	}
+	curves := []string{"P-256", "P-384", "P-521"}
+	for i, curve := range p.ECDSACurves {
+		supported := false
+		for _, c := range curves {
+			supported = supported || c == curve
+		}
+		if !supported {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("ecdsaCurves").Index(i), curve, curves))
+		}
+	}
+	if p.MaxSANs < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSANs"), p.MaxSANs, "must not be negative"))
+	}
+	return allErrs
+}
+
+func validateRetryPolicy(p RetryPolicy, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if p.MaxRetries != nil && *p.MaxRetries < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxRetries"), *p.MaxRetries, "must not be negative"))
+	}
+	if p.WaitMaxAttempts != nil && *p.WaitMaxAttempts < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("waitMaxAttempts"), *p.WaitMaxAttempts, "must be at least 1"))
+	}
+	durations := []struct {
+		name  string
+		value *metav1.Duration
+	}{
+		{"minRetryDelay", p.MinRetryDelay},
+		{"maxRetryDelay", p.MaxRetryDelay},
+		{"waitInterval", p.WaitInterval},
+	}
+	for _, d := range durations {
+		if d.value != nil && d.value.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(d.name), d.value.Duration.String(), "must be positive"))
+		}
+	}
+	if p.MinRetryDelay != nil && p.MaxRetryDelay != nil && p.MinRetryDelay.Duration > p.MaxRetryDelay.Duration {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxRetryDelay"), p.MaxRetryDelay.Duration.String(), "must not be less than minRetryDelay"))
+	}
+	return allErrs
+}
+
+// validateTemplateArn returns an error if the given string is not the ARN of
+// an AWS Private CA certificate template, e.g.
+// arn:aws:acm-pca:::template/EndEntityCertificate/V1
+func validateTemplateArn(s string) error {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a valid ARN: %v", s, err)
+	}
+	if parsed.Service != "acm-pca" || !strings.HasPrefix(parsed.Resource, "template/") {
+		return fmt.Errorf("%q is not the ARN of an AWS Private CA template", s)
+	}
+	return nil
+}
+
+// ValidateRegion returns an error if the given string is not the name of an
+// AWS region.
+func ValidateRegion(region string) error {
+	if !regionRegexp.MatchString(region) {
+		return fmt.Errorf("%q is not a valid AWS region", region)
+	}
+	return nil
+}
+
+// ParseCAArn parses the ARN of an AWS Private CA, e.g.
+// arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555
+func ParseCAArn(s string) (arn.ARN, error) {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return arn.ARN{}, fmt.Errorf("%q is not a valid ARN: %v", s, err)
+	}
+	if parsed.Service != "acm-pca" || !strings.HasPrefix(parsed.Resource, "certificate-authority/") {
+		return arn.ARN{}, fmt.Errorf("%q is not the ARN of an AWS Private CA", s)
+	}
+	if err := ValidateRegion(parsed.Region); err != nil {
+		return arn.ARN{}, fmt.Errorf("%q is not a valid ARN: %v", s, err)
+	}
+	return parsed, nil
+}
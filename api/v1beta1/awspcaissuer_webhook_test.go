@@ -0,0 +1,366 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	testArn          = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555"
+	testSecondaryArn = "arn:aws:acm-pca:us-west-2:123456789012:certificate-authority/66666666-7777-8888-9999-000000000000"
+)
+
+func TestAWSPCAIssuerDefault(t *testing.T) {
+	iss := &AWSPCAIssuer{
+		Spec: AWSPCAIssuerSpec{
+			SecretRef: &AWSCredentialsSecretReference{
+				Name:      "aws-credentials",
+				RegionRef: SecretKeySelector{Key: "aws-region"},
+			},
+		},
+	}
+	iss.Default()
+
+	p := iss.Spec.SecretRef
+	if p.AccessKeyRef.Key != DefaultAccessKeyKey || p.SecretKeyRef.Key != DefaultSecretKeyKey || p.ArnRef.Key != DefaultArnKey {
+		t.Errorf("Default() did not set the default keys: %+v", p)
+	}
+	if p.RegionRef.Key != "aws-region" {
+		t.Errorf("Default() overwrote regionRef.key, got %q", p.RegionRef.Key)
+	}
+
+	iss = &AWSPCAIssuer{
+		Spec: AWSPCAIssuerSpec{
+			Arn:       testArn,
+			SecretRef: &AWSCredentialsSecretReference{Name: "aws-credentials"},
+		},
+	}
+	iss.Default()
+	if p := iss.Spec.SecretRef; p.RegionRef.Key != "" || p.ArnRef.Key != "" {
+		t.Errorf("Default() set region or arn keys with an inline ARN: %+v", p)
+	}
+
+	iss = &AWSPCAIssuer{
+		Spec: AWSPCAIssuerSpec{
+			CertificateAuthorities: []CertificateAuthority{{Arn: testArn}},
+			SecretRef:              &AWSCredentialsSecretReference{Name: "aws-credentials"},
+		},
+	}
+	iss.Default()
+	if p := iss.Spec.SecretRef; p.RegionRef.Key != "" || p.ArnRef.Key != "" {
+		t.Errorf("Default() set region or arn keys with a list of CAs: %+v", p)
+	}
+
+	iss = &AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer"},
+		Spec:       AWSPCAIssuerSpec{Arn: testArn, TrustBundle: &TrustBundle{}},
+	}
+	iss.Default()
+	if tb := iss.Spec.TrustBundle; tb.ConfigMapName != "issuer-ca-bundle" || tb.Key != DefaultTrustBundleKey {
+		t.Errorf("Default() did not set the trust bundle defaults: %+v", tb)
+	}
+
+	iss = &AWSPCAIssuer{Spec: AWSPCAIssuerSpec{Arn: testArn}}
+	iss.Default()
+	if iss.Spec.SecretRef != nil {
+		t.Errorf("Default() set a secret reference: %+v", iss.Spec.SecretRef)
+	}
+}
+
+func TestAWSPCAIssuerValidateUpdate(t *testing.T) {
+	v := &AWSPCAIssuerValidator{}
+	ctx := context.Background()
+	oldIss := &AWSPCAIssuer{Spec: validSpec()}
+	oldIss.Spec.Arn = testArn
+
+	iss := oldIss.DeepCopy()
+	if err := v.ValidateUpdate(ctx, iss, oldIss); err != nil {
+		t.Errorf("ValidateUpdate() error = %v", err)
+	}
+
+	iss.Spec.Arn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/66666666-7777-8888-9999-000000000000"
+	if err := v.ValidateUpdate(ctx, iss, oldIss); err == nil {
+		t.Error("ValidateUpdate() allowed a change of spec.arn")
+	}
+
+	// The ARN can be moved to the list of CAs.
+	iss = oldIss.DeepCopy()
+	iss.Spec.Arn = ""
+	iss.Spec.SecretRef.RegionRef.Key, iss.Spec.SecretRef.ArnRef.Key = "", ""
+	iss.Spec.CertificateAuthorities = []CertificateAuthority{{Arn: testArn}, {Arn: testSecondaryArn}}
+	if err := v.ValidateUpdate(ctx, iss, oldIss); err != nil {
+		t.Errorf("ValidateUpdate() error = %v", err)
+	}
+	iss.Spec.CertificateAuthorities = []CertificateAuthority{{Arn: testSecondaryArn}}
+	if err := v.ValidateUpdate(ctx, iss, oldIss); err == nil {
+		t.Error("ValidateUpdate() allowed removing spec.arn")
+	}
+}
+
+func TestAWSPCAIssuerValidateUpdateSecretArn(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := func(name, caArn string) *core.Secret {
+		return &core.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Data:       map[string][]byte{"region": []byte("us-east-1"), "arn": []byte(caArn), "other": []byte(testSecondaryArn)},
+		}
+	}
+	v := &AWSPCAIssuerValidator{Client: fake.NewFakeClientWithScheme(scheme,
+		secret("aws-credentials", testArn), secret("rotated", testArn), secret("other-ca", testSecondaryArn))}
+	ctx := context.Background()
+	oldIss := &AWSPCAIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"}, Spec: validSpec()}
+
+	tests := []struct {
+		name    string
+		update  func(s *AWSPCAIssuerSpec)
+		wantErr bool
+	}{
+		{"unchanged", func(s *AWSPCAIssuerSpec) {}, false},
+		{"rotated secret", func(s *AWSPCAIssuerSpec) { s.SecretRef.Name = "rotated" }, false},
+		{"missing secret", func(s *AWSPCAIssuerSpec) { s.SecretRef.Name = "missing" }, false},
+		{"secret of another CA", func(s *AWSPCAIssuerSpec) { s.SecretRef.Name = "other-ca" }, true},
+		{"other key", func(s *AWSPCAIssuerSpec) { s.SecretRef.ArnRef.Key = "other" }, true},
+		{"moved to spec.arn", func(s *AWSPCAIssuerSpec) { s.Arn = testArn; s.SecretRef.ArnRef.Key = "" }, false},
+		{"spec.arn of another CA", func(s *AWSPCAIssuerSpec) { s.Arn = testSecondaryArn; s.SecretRef.ArnRef.Key = "" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iss := oldIss.DeepCopy()
+			tt.update(&iss.Spec)
+			if err := v.validateImmutableFields(ctx, iss, oldIss); (err != nil) != tt.wantErr {
+				t.Errorf("validateImmutableFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSPCAIssuerValidatorHandle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &AWSPCAIssuerValidator{}
+	if err := v.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := func(spec AWSPCAIssuerSpec) runtime.RawExtension {
+		iss := &AWSPCAIssuer{
+			TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "AWSPCAIssuer"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+			Spec:       spec,
+		}
+		data, err := json.Marshal(iss)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	request := func(op admissionv1beta1.Operation, spec, oldSpec AWSPCAIssuerSpec) admission.Request {
+		req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{Operation: op, Object: raw(spec)}}
+		if op == admissionv1beta1.Update {
+			req.OldObject = raw(oldSpec)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		req     admission.Request
+		allowed bool
+	}{
+		{"create", request(admissionv1beta1.Create, AWSPCAIssuerSpec{Arn: testArn}, AWSPCAIssuerSpec{}), true},
+		{"create invalid", request(admissionv1beta1.Create, AWSPCAIssuerSpec{}, AWSPCAIssuerSpec{}), false},
+		{"update", request(admissionv1beta1.Update, AWSPCAIssuerSpec{Arn: testArn, TemplateArn: "arn:aws:acm-pca:::template/EndEntityCertificate/V1"},
+			AWSPCAIssuerSpec{Arn: testArn}), true},
+		{"update arn", request(admissionv1beta1.Update, AWSPCAIssuerSpec{Arn: testSecondaryArn}, AWSPCAIssuerSpec{Arn: testArn}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := v.Handle(context.Background(), tt.req)
+			if resp.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %v, want %v: %v", resp.Allowed, tt.allowed, resp.Result)
+			}
+		})
+	}
+}
+
+func TestAWSPCAIssuerValidate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    AWSPCAIssuerSpec
+		secret  map[string]string
+		wantErr bool
+	}{
+		{"ok", validSpec(), map[string]string{"region": "us-east-1", "arn": testArn}, false},
+		{"ok without secret", validSpec(), nil, false},
+		{"missing name", func() AWSPCAIssuerSpec { s := validSpec(); s.SecretRef.Name = ""; return s }(), nil, true},
+		{"invalid key", func() AWSPCAIssuerSpec { s := validSpec(); s.SecretRef.ArnRef.Key = "a/b"; return s }(), nil, true},
+		{"negative threshold", func() AWSPCAIssuerSpec {
+			s := validSpec()
+			s.CAExpiryWarningThreshold = &metav1.Duration{Duration: -1}
+			return s
+		}(), nil, true},
+		{"invalid region", validSpec(), map[string]string{"region": "us-east", "arn": testArn}, true},
+		{"invalid arn", validSpec(), map[string]string{"region": "us-east-1", "arn": "arn:aws:acm:us-east-1:123456789012:certificate/1"}, true},
+		{"region mismatch", validSpec(), map[string]string{"region": "eu-west-1", "arn": testArn}, true},
+		{"inline arn", withInline("", testArn), nil, false},
+		{"inline region and arn", withInline("us-east-1", testArn), nil, false},
+		{"inline region mismatch", withInline("eu-west-1", testArn), nil, true},
+		{"inline region mismatch with secret", withInline("", testArn), map[string]string{"region": "eu-west-1"}, true},
+		{"inline invalid arn", withInline("", "arn:aws:acm-pca:us-east-1:123456789012:ca/1"), nil, true},
+		{"default credentials", AWSPCAIssuerSpec{Arn: testArn}, nil, false},
+		{"default credentials without arn", AWSPCAIssuerSpec{Region: "us-east-1"}, nil, true},
+		{"certificate authorities", withCAs(CertificateAuthority{Arn: testArn}, CertificateAuthority{Arn: testSecondaryArn, Region: "us-west-2"}), nil, false},
+		{"certificate authorities with default credentials", AWSPCAIssuerSpec{CertificateAuthorities: []CertificateAuthority{{Arn: testArn}}}, nil, false},
+		{"certificate authorities with arn", func() AWSPCAIssuerSpec { s := withCAs(CertificateAuthority{Arn: testArn}); s.Arn = testArn; return s }(), nil, true},
+		{"certificate authorities with arnRef", func() AWSPCAIssuerSpec {
+			s := withCAs(CertificateAuthority{Arn: testArn})
+			s.SecretRef.ArnRef.Key = "arn"
+			return s
+		}(), nil, true},
+		{"certificate authority without arn", withCAs(CertificateAuthority{Region: "us-east-1"}), nil, true},
+		{"certificate authority invalid arn", withCAs(CertificateAuthority{Arn: "arn:aws:acm-pca:us-east-1:123456789012:ca/1"}), nil, true},
+		{"certificate authority region mismatch", withCAs(CertificateAuthority{Arn: testArn, Region: "us-west-2"}), nil, true},
+		{"duplicate certificate authorities", withCAs(CertificateAuthority{Arn: testArn}, CertificateAuthority{Arn: testArn}), nil, true},
+		{"draining certificate authority", withCAs(CertificateAuthority{Arn: testArn, Weight: int32Ptr(1)}, CertificateAuthority{Arn: testSecondaryArn, State: CertificateAuthorityDraining}), nil, false},
+		{"only draining certificate authorities", withCAs(CertificateAuthority{Arn: testArn, State: CertificateAuthorityDraining}), nil, true},
+		{"invalid certificate authority state", withCAs(CertificateAuthority{Arn: testArn, State: "Disabled"}), nil, true},
+		{"template", AWSPCAIssuerSpec{Arn: testArn, TemplateArn: "arn:aws:acm-pca:::template/EndEntityCertificate_CSRPassthrough/V1"}, nil, false},
+		{"invalid template", AWSPCAIssuerSpec{Arn: testArn, TemplateArn: "arn:aws:acm-pca:::certificate-authority/1"}, nil, true},
+		{"subject", withSubject("arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1", SubjectTemplate{
+			Organization: "Example", Country: "US", CommonName: "{{ .CertificateName }}.{{ .Namespace }}"}), nil, false},
+		{"subject without passthrough template", withSubject("arn:aws:acm-pca:::template/EndEntityCertificate/V1", SubjectTemplate{Organization: "Example"}), nil, true},
+		{"subject invalid country", withSubject("arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1", SubjectTemplate{Country: "USA"}), nil, true},
+		{"subject invalid template", withSubject("arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1", SubjectTemplate{CommonName: "{{ .Name"}), nil, true},
+		{"csr policy", AWSPCAIssuerSpec{Arn: testArn, CSRPolicy: &CSRPolicy{MinRSAKeySize: 3072, ECDSACurves: []string{"P-384"}, MaxSANs: 10}}, nil, false},
+		{"csr policy small rsa key", AWSPCAIssuerSpec{Arn: testArn, CSRPolicy: &CSRPolicy{MinRSAKeySize: 1024}}, nil, true},
+		{"csr policy unsupported curve", AWSPCAIssuerSpec{Arn: testArn, CSRPolicy: &CSRPolicy{ECDSACurves: []string{"P-224"}}}, nil, true},
+		{"csr policy negative max sans", AWSPCAIssuerSpec{Arn: testArn, CSRPolicy: &CSRPolicy{MaxSANs: -1}}, nil, true},
+		{"spiffe", AWSPCAIssuerSpec{Arn: testArn, SPIFFE: &SPIFFEConfig{TrustDomain: "example.org"}}, nil, false},
+		{"spiffe invalid trust domain", AWSPCAIssuerSpec{Arn: testArn, SPIFFE: &SPIFFEConfig{TrustDomain: "Example.org"}}, nil, true},
+		{"spiffe empty trust domain", AWSPCAIssuerSpec{Arn: testArn, SPIFFE: &SPIFFEConfig{}}, nil, true},
+		{"trust bundle", withTrustBundle(TrustBundle{ConfigMapName: "ca-bundle", Key: "ca.crt", Namespaces: []string{"default"},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"trust": "awspca"}}}), nil, false},
+		{"trust bundle without key", withTrustBundle(TrustBundle{ConfigMapName: "ca-bundle"}), nil, true},
+		{"trust bundle invalid name", withTrustBundle(TrustBundle{ConfigMapName: "CA", Key: "ca.crt"}), nil, true},
+		{"trust bundle invalid namespace", withTrustBundle(TrustBundle{ConfigMapName: "ca-bundle", Key: "ca.crt", Namespaces: []string{"a.b"}}), nil, true},
+		{"trust bundle invalid selector", withTrustBundle(TrustBundle{ConfigMapName: "ca-bundle", Key: "ca.crt",
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "trust", Operator: "Foo"}}}}), nil, true},
+		{"negative certificate authority weight", withCAs(CertificateAuthority{Arn: testArn, Weight: int32Ptr(-1)}), nil, true},
+		{"retry policy", withRetryPolicy(RetryPolicy{MaxRetries: int32Ptr(0), MinRetryDelay: &metav1.Duration{Duration: time.Second},
+			MaxRetryDelay: &metav1.Duration{Duration: time.Minute}, WaitInterval: &metav1.Duration{Duration: time.Second}, WaitMaxAttempts: int32Ptr(10)}), nil, false},
+		{"retry policy negative max retries", withRetryPolicy(RetryPolicy{MaxRetries: int32Ptr(-1)}), nil, true},
+		{"retry policy no wait attempts", withRetryPolicy(RetryPolicy{WaitMaxAttempts: int32Ptr(0)}), nil, true},
+		{"retry policy zero wait interval", withRetryPolicy(RetryPolicy{WaitInterval: &metav1.Duration{}}), nil, true},
+		{"retry policy inverted delays", withRetryPolicy(RetryPolicy{MinRetryDelay: &metav1.Duration{Duration: time.Minute},
+			MaxRetryDelay: &metav1.Duration{Duration: time.Second}}), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if tt.secret != nil {
+				secret := &core.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "aws-credentials"},
+					Data:       map[string][]byte{},
+				}
+				for k, v := range tt.secret {
+					secret.Data[k] = []byte(v)
+				}
+				objs = append(objs, secret)
+			}
+			v := &AWSPCAIssuerValidator{Client: fake.NewFakeClientWithScheme(scheme, objs...)}
+
+			iss := &AWSPCAIssuer{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+				Spec:       tt.spec,
+			}
+			if err := v.ValidateCreate(context.Background(), iss); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func validSpec() AWSPCAIssuerSpec {
+	return AWSPCAIssuerSpec{
+		SecretRef: &AWSCredentialsSecretReference{
+			Name:         "aws-credentials",
+			AccessKeyRef: SecretKeySelector{Key: "accesskey"},
+			SecretKeyRef: SecretKeySelector{Key: "secretkey"},
+			RegionRef:    SecretKeySelector{Key: "region"},
+			ArnRef:       SecretKeySelector{Key: "arn"},
+		},
+	}
+}
+
+func withInline(region, arn string) AWSPCAIssuerSpec {
+	s := validSpec()
+	s.Region = region
+	s.Arn = arn
+	s.SecretRef.ArnRef.Key = ""
+	return s
+}
+
+func withCAs(cas ...CertificateAuthority) AWSPCAIssuerSpec {
+	s := validSpec()
+	s.CertificateAuthorities = cas
+	s.SecretRef.RegionRef.Key = ""
+	s.SecretRef.ArnRef.Key = ""
+	return s
+}
+
+func withSubject(templateArn string, subject SubjectTemplate) AWSPCAIssuerSpec {
+	return AWSPCAIssuerSpec{Arn: testArn, TemplateArn: templateArn, Subject: &subject}
+}
+
+func withTrustBundle(tb TrustBundle) AWSPCAIssuerSpec {
+	s := AWSPCAIssuerSpec{Arn: testArn}
+	s.TrustBundle = &tb
+	return s
+}
+
+func withRetryPolicy(p RetryPolicy) AWSPCAIssuerSpec {
+	return AWSPCAIssuerSpec{Arn: testArn, RetryPolicy: &p}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
@@ -0,0 +1,87 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit contains the audit trail of the certificates signed by the
+// AWSPCA issuer. A record is written for every sign attempt to a sink
+// configured with --audit-sink, separately from the logs of the controller.
+package audit
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/provisioners"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// certificateNameAnnotation is set by cert-manager on the CertificateRequests
+// created for a Certificate.
+const certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+var errNoPEM = errors.New("no PEM data found")
+
+// Record is the audit record of a sign attempt.
+type Record = api.AWSPCAAuditRecordSpec
+
+// NewRecord returns the audit record of the sign attempt of the given
+// CertificateRequest by the given issuer. The serial number, SANs and
+// validity are the ones of the issued certificate; if the attempt failed, the
+// SANs are read from the CSR.
+func NewRecord(now time.Time, cr *certmanager.CertificateRequest, issuer types.NamespacedName, cert *provisioners.Certificate, err error) *Record {
+	r := &Record{
+		Time:            metav1.NewTime(now),
+		Namespace:       cr.Namespace,
+		Name:            cr.Name,
+		UID:             string(cr.UID),
+		CertificateName: cr.Annotations[certificateNameAnnotation],
+		Issuer:          issuer.String(),
+		Outcome:         api.AuditOutcomeIssued,
+	}
+	if err != nil {
+		r.Outcome = api.AuditOutcomeFailed
+		r.Error = err.Error()
+	}
+
+	if cert == nil {
+		if csr, err := parseCSR(cr.Spec.Request); err == nil {
+			r.SANs = provisioners.SubjectAlternativeNames(csr)
+		}
+		return r
+	}
+
+	r.CAArn = cert.CAArn
+	r.CertificateArn = cert.Arn
+	r.SerialNumber = cert.SerialNumber
+	r.SANs = cert.SANs
+	if !cert.NotAfter.IsZero() {
+		notBefore, notAfter := metav1.NewTime(cert.NotBefore), metav1.NewTime(cert.NotAfter)
+		r.NotBefore, r.NotAfter = &notBefore, &notAfter
+	}
+	return r
+}
+
+// parseCSR parses the PEM encoded certificate request.
+func parseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errNoPEM
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
@@ -0,0 +1,161 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/provisioners"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNewRecord(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		DNSNames: []string{"requested.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := &certmanager.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "backend-1234",
+			UID:         "uid",
+			Annotations: map[string]string{certificateNameAnnotation: "backend"},
+		},
+		Spec: certmanager.CertificateRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		},
+	}
+	issuer := types.NamespacedName{Namespace: "default", Name: "issuer"}
+	now := time.Date(2021, 3, 4, 10, 11, 12, 0, time.UTC)
+
+	notBefore := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+	cert := &provisioners.Certificate{
+		Arn:          "certificate-arn",
+		CAArn:        "ca-arn",
+		SerialNumber: "abc",
+		SANs:         []string{"backend.example.com", "10.0.0.1"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	got := NewRecord(now, cr, issuer, cert, nil)
+	before, after := metav1.NewTime(notBefore), metav1.NewTime(notAfter)
+	want := &Record{
+		Time:            metav1.NewTime(now),
+		Namespace:       "default",
+		Name:            "backend-1234",
+		UID:             "uid",
+		CertificateName: "backend",
+		Issuer:          "default/issuer",
+		CAArn:           "ca-arn",
+		CertificateArn:  "certificate-arn",
+		SerialNumber:    "abc",
+		SANs:            []string{"backend.example.com", "10.0.0.1"},
+		NotBefore:       &before,
+		NotAfter:        &after,
+		Outcome:         api.AuditOutcomeIssued,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewRecord() = %+v, want %+v", got, want)
+	}
+
+	// A failed attempt records the error and the SANs of the CSR.
+	got = NewRecord(now, cr, issuer, nil, errors.New("boom"))
+	want = &Record{
+		Time:            metav1.NewTime(now),
+		Namespace:       "default",
+		Name:            "backend-1234",
+		UID:             "uid",
+		CertificateName: "backend",
+		Issuer:          "default/issuer",
+		SANs:            []string{"requested.example.com"},
+		Outcome:         api.AuditOutcomeFailed,
+		Error:           "boom",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewRecord() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+	for _, name := range []string{"first", "second"} {
+		r := &Record{Namespace: "default", Name: name, Outcome: api.AuditOutcomeIssued}
+		if err := sink.Write(context.Background(), r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Write() wrote %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var r Record
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[1], err)
+	}
+	if r.Name != "second" || r.Outcome != api.AuditOutcomeIssued {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantNil bool
+		wantErr bool
+	}{
+		{value: "", wantNil: true},
+		{value: "stdout"},
+		{value: "resource"},
+		{value: "file:" + t.TempDir() + "/audit.log"},
+		{value: "file:", wantErr: true},
+		{value: "syslog", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			sink, err := NewSink(tt.value, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (sink == nil) != tt.wantNil {
+				t.Errorf("NewSink() = %v, want nil %v", sink, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Sink writes audit records.
+type Sink interface {
+	Write(ctx context.Context, r *Record) error
+}
+
+// NewSink returns the sink configured by the value of --audit-sink:
+//   - "stdout" writes the records as JSON lines to the standard output,
+//   - "file:<path>" appends the records as JSON lines to the given file,
+//   - "resource" creates an AWSPCAAuditRecord resource for every record.
+//
+// An empty value disables the audit trail, and nil is returned.
+func NewSink(value string, c client.Client) (Sink, error) {
+	switch {
+	case value == "":
+		return nil, nil
+	case value == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(value, "file:"):
+		path := strings.TrimPrefix(value, "file:")
+		if path == "" {
+			return nil, fmt.Errorf("invalid audit sink %q: missing file path", value)
+		}
+		return NewFileSink(path)
+	case value == "resource":
+		return NewResourceSink(c), nil
+	default:
+		return nil, fmt.Errorf("invalid audit sink %q: must be stdout, file:<path> or resource", value)
+	}
+}
+
+// writerSink writes the records as JSON lines.
+type writerSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterSink returns a sink writing the records as JSON lines to w.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{enc: json.NewEncoder(w)}
+}
+
+// Write implements Sink.
+func (s *writerSink) Write(ctx context.Context, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+// NewFileSink returns a sink appending the records as JSON lines to the
+// file at the given path, which is created if it does not exist.
+func NewFileSink(path string) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %v", err)
+	}
+	return NewWriterSink(f), nil
+}
+
+// resourceSink creates an AWSPCAAuditRecord resource for every record.
+type resourceSink struct {
+	client client.Client
+}
+
+// NewResourceSink returns a sink creating an AWSPCAAuditRecord resource for
+// every record. The resources are not owned by the CertificateRequests, so
+// that they are kept when the requests are deleted.
+func NewResourceSink(c client.Client) Sink {
+	return &resourceSink{client: c}
+}
+
+// Write implements Sink.
+func (s *resourceSink) Write(ctx context.Context, r *Record) error {
+	rec := &api.AWSPCAAuditRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: r.Namespace + "-" + r.Name + "-",
+		},
+		Spec: *r,
+	}
+	if err := s.client.Create(ctx, rec); err != nil {
+		return fmt.Errorf("failed to create AWSPCAAuditRecord: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The reasons of the Ready condition of a CertificateRequest understood by
+// cert-manager.
+const (
+	// CertificateRequestReasonPending means the certificate is not issued
+	// yet, cert-manager keeps waiting for it.
+	CertificateRequestReasonPending = "Pending"
+	// CertificateRequestReasonFailed means the certificate will never be
+	// issued, cert-manager stops waiting for it.
+	CertificateRequestReasonFailed = "Failed"
+	// CertificateRequestReasonIssued means the certificate is issued.
+	CertificateRequestReasonIssued = "Issued"
+	// CertificateRequestReasonDenied is the reason of the Ready condition
+	// set by cert-manager on denied CertificateRequests.
+	CertificateRequestReasonDenied = "Denied"
+)
+
+// +kubebuilder:object:root=true
+
+// CertificateRequest is a request from cert-manager to an issuer to sign a
+// certificate signing request.
+type CertificateRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CertificateRequestSpec   `json:"spec"`
+	Status CertificateRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CertificateRequestList contains a list of CertificateRequest.
+type CertificateRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CertificateRequest `json:"items"`
+}
+
+// CertificateRequestSpec defines the desired state of CertificateRequest.
+type CertificateRequestSpec struct {
+	// Duration is the requested validity of the certificate.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// IssuerRef references the issuer that signs the request.
+	IssuerRef cmmeta.ObjectReference `json:"issuerRef"`
+
+	// Request is the PEM encoded certificate signing request.
+	Request []byte `json:"request"`
+
+	// IsCA requests a CA certificate.
+	// +optional
+	IsCA bool `json:"isCA,omitempty"`
+
+	// Usages are the key usages requested for the certificate.
+	// +optional
+	Usages []KeyUsage `json:"usages,omitempty"`
+
+	// Username, UID, Groups and Extra identify the user that created the
+	// CertificateRequest. They are set by cert-manager and cannot be
+	// changed.
+	// +optional
+	Username string `json:"username,omitempty"`
+	// +optional
+	UID string `json:"uid,omitempty"`
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// +optional
+	Extra map[string][]string `json:"extra,omitempty"`
+}
+
+// CertificateRequestStatus defines the observed state of CertificateRequest.
+type CertificateRequestStatus struct {
+	// +optional
+	Conditions []CertificateRequestCondition `json:"conditions,omitempty"`
+
+	// Certificate is the PEM encoded certificate issued for the request.
+	// +optional
+	Certificate []byte `json:"certificate,omitempty"`
+
+	// CA is the PEM encoded certificate of the CA that signed the
+	// certificate, or a bundle of CA certificates.
+	// +optional
+	CA []byte `json:"ca,omitempty"`
+
+	// FailureTime is the time the request failed, used by cert-manager to
+	// back off before creating a new request.
+	// +optional
+	FailureTime *metav1.Time `json:"failureTime,omitempty"`
+}
+
+// CertificateRequestCondition is a condition of a CertificateRequest.
+type CertificateRequestCondition struct {
+	Type   CertificateRequestConditionType `json:"type"`
+	Status cmmeta.ConditionStatus          `json:"status"`
+
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CertificateRequestConditionType is the type of a condition of a
+// CertificateRequest.
+type CertificateRequestConditionType string
+
+const (
+	// CertificateRequestConditionReady is set by the issuer, True once the
+	// certificate is issued.
+	CertificateRequestConditionReady CertificateRequestConditionType = "Ready"
+	// CertificateRequestConditionInvalidRequest is set by the issuer when it
+	// will never sign the request.
+	CertificateRequestConditionInvalidRequest CertificateRequestConditionType = "InvalidRequest"
+	// CertificateRequestConditionApproved is set by an approver when the
+	// request may be signed.
+	CertificateRequestConditionApproved CertificateRequestConditionType = "Approved"
+	// CertificateRequestConditionDenied is set by an approver when the
+	// request must not be signed.
+	CertificateRequestConditionDenied CertificateRequestConditionType = "Denied"
+)
+
+// KeyUsage is a usage of the key of a certificate, e.g. "digital signature"
+// or "server auth".
+type KeyUsage string
+
+func init() {
+	SchemeBuilder.Register(&CertificateRequest{}, &CertificateRequestList{})
+}
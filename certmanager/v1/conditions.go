@@ -0,0 +1,69 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HasCondition returns true if the CertificateRequest has a condition of the
+// given type and status.
+func HasCondition(cr *CertificateRequest, conditionType CertificateRequestConditionType, status cmmeta.ConditionStatus) bool {
+	for _, cond := range cr.Status.Conditions {
+		if cond.Type == conditionType && cond.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadyReason returns the reason of the Ready condition of the
+// CertificateRequest, or an empty string if it has no Ready condition.
+func ReadyReason(cr *CertificateRequest) string {
+	for _, cond := range cr.Status.Conditions {
+		if cond.Type == CertificateRequestConditionReady {
+			return cond.Reason
+		}
+	}
+	return ""
+}
+
+// SetCondition sets the condition of the given type of the
+// CertificateRequest. The last transition time is only updated if the
+// status of the condition changes.
+func SetCondition(cr *CertificateRequest, conditionType CertificateRequestConditionType, status cmmeta.ConditionStatus, reason, message string) {
+	now := metav1.Now()
+	newCondition := CertificateRequestCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: &now,
+		Reason:             reason,
+		Message:            message,
+	}
+
+	for i, cond := range cr.Status.Conditions {
+		if cond.Type != conditionType {
+			continue
+		}
+		if cond.Status == status {
+			newCondition.LastTransitionTime = cond.LastTransitionTime
+		}
+		cr.Status.Conditions[i] = newCondition
+		return
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, newCondition)
+}
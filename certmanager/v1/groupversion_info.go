@@ -0,0 +1,43 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1 contains the subset of the cert-manager.io/v1 API used by the
+// controllers: the CertificateRequest resource and its conditions.
+//
+// The types follow the v1 API of cert-manager v1.5. They are copied rather
+// than imported because the cert-manager modules that provide the v1 API
+// require newer Kubernetes libraries than the controller-runtime version of
+// this project. Replace this package with
+// github.com/jetstack/cert-manager/pkg/apis/certmanager/v1 once the
+// dependencies are upgraded.
+// +kubebuilder:object:generate=true
+// +kubebuilder:skip
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "cert-manager.io", Version: "v1"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,100 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runCertificates lists the AWSPCAIssuedCertificates of the namespace, or of
+// all namespaces, by expiry.
+func runCertificates(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("certificates", flag.ContinueOnError)
+	allNamespaces := fs.Bool("all-namespaces", false, "List the certificates of all namespaces.")
+	issuer := fs.String("issuer", "", "Only list the certificates issued by this issuer.")
+	certificate := fs.String("certificate", "", "Only list the certificates issued for this Certificate.")
+	san := fs.String("san", "", "Only list the certificates with this SAN.")
+	expiringWithin := fs.Duration("expiring-within", 0, "Only list the valid certificates expiring within this duration, e.g. 720h.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var opts []client.ListOption
+	if !*allNamespaces {
+		opts = append(opts, client.InNamespace(env.namespace))
+	}
+	labels := client.MatchingLabels{}
+	if *issuer != "" {
+		labels[api.IssuerLabel] = *issuer
+	}
+	if *certificate != "" {
+		labels[api.CertificateNameLabel] = *certificate
+	}
+	opts = append(opts, labels)
+
+	var list api.AWSPCAIssuedCertificateList
+	if err := env.client.List(ctx, &list, opts...); err != nil {
+		return err
+	}
+	certs := filterCertificates(list.Items, *san, *expiringWithin, time.Now())
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].Spec.NotAfter.Before(&certs[j].Spec.NotAfter)
+	})
+
+	w := tabwriter.NewWriter(env.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSERIAL\tISSUER\tCERTIFICATE\tSANS\tNOT AFTER\tSTATE")
+	for _, c := range certs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Namespace, c.Spec.SerialNumber, c.Spec.IssuerName, orDash(c.Spec.CertificateName),
+			orDash(strings.Join(c.Spec.SANs, ",")), c.Spec.NotAfter.UTC().Format(time.RFC3339), orDash(string(c.Status.State)))
+	}
+	return w.Flush()
+}
+
+// filterCertificates returns the certificates with the given SAN, if not
+// empty, and the valid certificates expiring within the given duration, if
+// not zero.
+func filterCertificates(certs []api.AWSPCAIssuedCertificate, san string, expiringWithin time.Duration, now time.Time) []api.AWSPCAIssuedCertificate {
+	var filtered []api.AWSPCAIssuedCertificate
+	for _, c := range certs {
+		if san != "" && !containsString(c.Spec.SANs, san) {
+			continue
+		}
+		if expiringWithin > 0 && (c.Status.State == api.IssuedCertificateStateRevoked ||
+			!c.Spec.NotAfter.Time.After(now) || c.Spec.NotAfter.Time.After(now.Add(expiringWithin))) {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func issuedCertificate(serial, certificateRequest string, notAfter time.Time, sans ...string) *api.AWSPCAIssuedCertificate {
+	return &api.AWSPCAIssuedCertificate{
+		ObjectMeta: meta.ObjectMeta{Namespace: "default", Name: serial},
+		Spec: api.AWSPCAIssuedCertificateSpec{
+			SerialNumber:           serial,
+			CertificateRequestName: certificateRequest,
+			SANs:                   sans,
+			NotAfter:               meta.NewTime(notAfter),
+		},
+	}
+}
+
+func TestFilterCertificates(t *testing.T) {
+	now := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	expired := issuedCertificate("1", "a", now.Add(-time.Hour), "a.example.com")
+	soon := issuedCertificate("2", "b", now.Add(time.Hour), "b.example.com")
+	later := issuedCertificate("3", "c", now.Add(48*time.Hour), "b.example.com")
+	revoked := issuedCertificate("4", "d", now.Add(time.Hour))
+	revoked.Status.State = api.IssuedCertificateStateRevoked
+	certs := []api.AWSPCAIssuedCertificate{*expired, *soon, *later, *revoked}
+
+	names := func(certs []api.AWSPCAIssuedCertificate) []string {
+		var names []string
+		for _, c := range certs {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	if got := names(filterCertificates(certs, "", 0, now)); len(got) != 4 {
+		t.Errorf("filterCertificates() = %v, want all the certificates", got)
+	}
+	if got := names(filterCertificates(certs, "b.example.com", 0, now)); len(got) != 2 || got[0] != "2" || got[1] != "3" {
+		t.Errorf("filterCertificates() by SAN = %v, want [2 3]", got)
+	}
+	if got := names(filterCertificates(certs, "", 24*time.Hour, now)); len(got) != 1 || got[0] != "2" {
+		t.Errorf("filterCertificates() expiring = %v, want [2]", got)
+	}
+}
+
+func TestFindIssuedCertificate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := api.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	c := fake.NewFakeClientWithScheme(scheme,
+		issuedCertificate("abc", "backend-1", now),
+		issuedCertificate("def", "backend-2", now),
+	)
+	ctx := context.Background()
+
+	for _, serial := range []string{"abc", "0a:bc", "0ABC"} {
+		ic, err := findIssuedCertificate(ctx, c, "default", serial, "")
+		if err != nil || ic.Name != "abc" {
+			t.Errorf("findIssuedCertificate(%q) = %v, %v, want abc", serial, ic, err)
+		}
+	}
+	for _, serial := range []string{"0", "00", "0x", "ab:cg", "-abc"} {
+		if _, err := findIssuedCertificate(ctx, c, "default", serial, ""); err == nil || !strings.Contains(err.Error(), "invalid serial number") {
+			t.Errorf("findIssuedCertificate(%q) error = %v, want an invalid serial number", serial, err)
+		}
+	}
+	if ic, err := findIssuedCertificate(ctx, c, "default", "", "backend-2"); err != nil || ic.Name != "def" {
+		t.Errorf("findIssuedCertificate() by CertificateRequest = %v, %v, want def", ic, err)
+	}
+	if _, err := findIssuedCertificate(ctx, c, "default", "", "backend-3"); err == nil {
+		t.Error("findIssuedCertificate() found a certificate for an unknown CertificateRequest")
+	}
+	if _, err := findIssuedCertificate(ctx, c, "other", "abc", ""); err == nil {
+		t.Error("findIssuedCertificate() found a certificate in another namespace")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-awspca is a kubectl plugin to inspect and operate AWSPCAIssuers.
+// Installed in the PATH, it is run as `kubectl awspca`.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/provisioners"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const usage = `Inspect and operate AWSPCAIssuers.
+
+Usage:
+  kubectl awspca [flags] <command> [command flags]
+
+Commands:
+  status <issuer>         Show the health of an issuer and the details of its private CAs
+  certificates            List the certificates issued by the issuers of the namespace
+  revoke                  Revoke a certificate by serial number or CertificateRequest
+  sign <issuer> <csr>     Check how a CSR would be signed by an issuer, without issuing it
+
+Flags:
+`
+
+// command is a subcommand of the plugin.
+type command struct {
+	name string
+	run  func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{"status", runStatus},
+	{"certificates", runCertificates},
+	{"revoke", runRevoke},
+	{"sign", runSign},
+}
+
+// env is the environment of a command.
+type env struct {
+	client    client.Client
+	namespace string
+	out       io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("kubectl-awspca", flag.ContinueOnError)
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+	fs.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&overrides.CurrentContext, "context", "", "The kubeconfig context to use.")
+	fs.StringVar(&overrides.Context.Namespace, "namespace", "", "The namespace of the issuers, the namespace of the context if empty.")
+	fs.StringVar(&overrides.Context.Namespace, "n", "", "Shorthand for --namespace.")
+	awsEndpoint := fs.String("aws-endpoint", "", "URL of the AWS Private CA API to use instead of the regional endpoints of AWS.")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	if *awsEndpoint != "" {
+		provisioners.SetEndpoint(*awsEndpoint)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == fs.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return err
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = certmanager.AddToScheme(scheme)
+	_ = api.AddToScheme(scheme)
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	return cmd.run(context.Background(), &env{client: c, namespace: namespace, out: os.Stdout}, fs.Args()[1:])
+}
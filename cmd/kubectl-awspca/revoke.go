@@ -0,0 +1,91 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runRevoke requests the revocation of a certificate by setting
+// spec.revocation on its AWSPCAIssuedCertificate. The certificate is revoked
+// by the controller.
+func runRevoke(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	serial := fs.String("serial", "", "The serial number of the certificate to revoke, in hexadecimal.")
+	certificateRequest := fs.String("certificate-request", "", "The name of the CertificateRequest of the certificate to revoke.")
+	reason := fs.String("reason", string(api.RevocationReasonUnspecified), "The revocation reason, e.g. KEY_COMPROMISE or SUPERSEDED.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*serial == "") == (*certificateRequest == "") {
+		return fmt.Errorf("exactly one of --serial or --certificate-request must be set")
+	}
+
+	ic, err := findIssuedCertificate(ctx, env.client, env.namespace, *serial, *certificateRequest)
+	if err != nil {
+		return err
+	}
+	if ic.Status.State == api.IssuedCertificateStateRevoked {
+		fmt.Fprintf(env.out, "Certificate %s is already revoked\n", ic.Spec.SerialNumber)
+		return nil
+	}
+
+	patch := client.MergeFrom(ic.DeepCopy())
+	ic.Spec.Revocation = &api.Revocation{Reason: api.RevocationReason(strings.ToUpper(*reason))}
+	if err := env.client.Patch(ctx, ic, patch); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.out, "Revocation of certificate %s requested, check its state with: kubectl get awspcaissuedcertificate -n %s %s\n",
+		ic.Spec.SerialNumber, ic.Namespace, ic.Name)
+	return nil
+}
+
+// findIssuedCertificate returns the AWSPCAIssuedCertificate with the given
+// serial number, or issued for the given CertificateRequest.
+func findIssuedCertificate(ctx context.Context, c client.Client, namespace, serial, certificateRequest string) (*api.AWSPCAIssuedCertificate, error) {
+	if serial != "" {
+		// The resources are named after the serial number in lowercase
+		// hexadecimal, without leading zeros, as formatted by big.Int.
+		n, ok := new(big.Int).SetString(strings.Replace(serial, ":", "", -1), 16)
+		if !ok || n.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid serial number %q, must be a positive hexadecimal number", serial)
+		}
+		ic := new(api.AWSPCAIssuedCertificate)
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: n.Text(16)}, ic); err != nil {
+			return nil, err
+		}
+		return ic, nil
+	}
+
+	var list api.AWSPCAIssuedCertificateList
+	if err := c.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		if list.Items[i].Spec.CertificateRequestName == certificateRequest {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no certificate issued for CertificateRequest %s/%s", namespace, certificateRequest)
+}
@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/provisioners"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// runSign checks a CSR against the configuration of an issuer, its CSR
+// policy and subject, and shows how it would be signed. It never issues a
+// certificate.
+func runSign(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	duration := fs.Duration("duration", 90*24*time.Hour, "The requested duration of the certificate.")
+	certificateName := fs.String("certificate", "", "The name of the Certificate the CSR would be created for, used by subject templates.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("usage: kubectl awspca sign <issuer> <csr file>")
+	}
+
+	csr, err := ioutil.ReadFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	iss := new(api.AWSPCAIssuer)
+	if err := env.client.Get(ctx, types.NamespacedName{Namespace: env.namespace, Name: fs.Arg(0)}, iss); err != nil {
+		return err
+	}
+	p, err := provisioners.FromIssuer(ctx, env.client, iss, true)
+	if err != nil {
+		return err
+	}
+
+	cr := newCertificateRequest(env.namespace, iss.Name, *certificateName, csr, *duration)
+	plan, err := p.Plan(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "The CSR would be signed by AWSPCAIssuer %s/%s (dry run, no certificate issued)\n", iss.Namespace, iss.Name)
+	var cas []string
+	for _, ca := range plan.CertificateAuthorities {
+		cas = append(cas, ca.Arn)
+	}
+	fmt.Fprintf(env.out, "Private CAs:\t%s\n", orDash(strings.Join(cas, ", ")))
+	fmt.Fprintf(env.out, "Template:\t%s\n", orDash(plan.TemplateArn))
+	subject := plan.Subject()
+	if subject == "" {
+		subject = "subject of the CSR"
+	}
+	fmt.Fprintf(env.out, "Subject:\t%s\n", subject)
+	fmt.Fprintf(env.out, "SANs:\t\t%s\n", orDash(strings.Join(plan.SANs, ", ")))
+	fmt.Fprintf(env.out, "Validity:\t%d days\n", int64(duration.Hours()/24))
+	if len(plan.CertificateAuthorities) == 0 {
+		return fmt.Errorf("the issuer has no active AWS Private CA")
+	}
+	return nil
+}
+
+// newCertificateRequest returns the CertificateRequest cert-manager would
+// create for the given CSR.
+func newCertificateRequest(namespace, issuer, certificateName string, csr []byte, duration time.Duration) *certmanager.CertificateRequest {
+	cr := &certmanager.CertificateRequest{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: namespace,
+			Name:      "kubectl-awspca-dry-run",
+		},
+		Spec: certmanager.CertificateRequestSpec{
+			Duration:  &meta.Duration{Duration: duration},
+			IssuerRef: cmmeta.ObjectReference{Name: issuer, Kind: "AWSPCAIssuer", Group: api.GroupVersion.Group},
+			Request:   csr,
+		},
+	}
+	if certificateName != "" {
+		cr.Name = certificateName + "-dry-run"
+		cr.Annotations = map[string]string{"cert-manager.io/certificate-name": certificateName}
+	}
+	return cr
+}
@@ -0,0 +1,133 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/provisioners"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// runStatus shows the conditions of an issuer and the status of its private
+// CAs. The CAs are also described with AWS Private CA, using the credentials
+// of the issuer, unless --describe=false.
+func runStatus(ctx context.Context, env *env, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	describe := fs.Bool("describe", true, "Describe the private CAs with AWS Private CA, using the credentials of the issuer.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: kubectl awspca status <issuer>")
+	}
+
+	iss := new(api.AWSPCAIssuer)
+	key := types.NamespacedName{Namespace: env.namespace, Name: fs.Arg(0)}
+	if err := env.client.Get(ctx, key, iss); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "Issuer:\t%s\n", key)
+	if iss.Spec.TemplateArn != "" {
+		fmt.Fprintf(env.out, "Template:\t%s\n", iss.Spec.TemplateArn)
+	}
+	for _, c := range iss.Status.Conditions {
+		fmt.Fprintf(env.out, "%s:\t%s (%s) %s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	fmt.Fprintln(env.out)
+
+	// The live details of the CAs, by ARN.
+	details := make(map[string]string)
+	var errs []error
+	if *describe {
+		p, err := provisioners.FromIssuer(ctx, env.client, iss, true)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, ca := range p.CertificateAuthorities() {
+				desc, err := p.DescribeCertificateAuthority(ctx, ca)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to describe %s: %v", ca.Arn, err))
+					continue
+				}
+				subject := ""
+				if cfg := desc.CertificateAuthorityConfiguration; cfg != nil && cfg.Subject != nil {
+					subject = aws.StringValue(cfg.Subject.CommonName)
+				}
+				details[ca.Arn] = fmt.Sprintf("%s\t%s\t%s", aws.StringValue(desc.Status), aws.StringValue(desc.Type), subject)
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(env.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ARN\tSTATE\tREADY\tREASON\tNOT AFTER\tAWS STATUS\tTYPE\tSUBJECT")
+	for _, ca := range issuerCAs(iss) {
+		status := api.CertificateAuthorityStatus{Arn: ca.Arn}
+		for _, s := range iss.Status.CertificateAuthorities {
+			if s.Arn == ca.Arn {
+				status = s
+			}
+		}
+		notAfter := "-"
+		if status.NotAfter != nil {
+			notAfter = status.NotAfter.UTC().Format(time.RFC3339)
+		}
+		detail, ok := details[ca.Arn]
+		if !ok {
+			detail = "-\t-\t-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ca.Arn, orDash(string(ca.State)), orDash(string(status.Ready)), orDash(string(status.Reason)), notAfter, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		fmt.Fprintf(env.out, "\nWarning: %v\n", err)
+	}
+	return nil
+}
+
+// issuerCAs returns the private CAs of the issuer. The CA of an issuer
+// configured with spec.arn, or with an ARN in its secret, is only known from
+// its status.
+func issuerCAs(iss *api.AWSPCAIssuer) []api.CertificateAuthority {
+	if len(iss.Spec.CertificateAuthorities) > 0 {
+		return iss.Spec.CertificateAuthorities
+	}
+	var cas []api.CertificateAuthority
+	for _, s := range iss.Status.CertificateAuthorities {
+		cas = append(cas, api.CertificateAuthority{Arn: s.Arn, State: api.CertificateAuthorityActive})
+	}
+	if len(cas) == 0 && iss.Spec.Arn != "" {
+		cas = append(cas, api.CertificateAuthority{Arn: iss.Spec.Arn, State: api.CertificateAuthorityActive})
+	}
+	return cas
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
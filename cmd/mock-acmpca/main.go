@@ -0,0 +1,77 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// mock-acmpca serves a mock of the AWS Private CA API backed by local CAs,
+// for integration tests. Point the controller at it with --aws-endpoint.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/awspca-issuer/mockacmpca"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultCAArn is the ARN of the CA served when no --ca flag is given.
+const defaultCAArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var addr string
+	var cas, disabledCAs stringList
+	var config mockacmpca.Config
+	var latency, issueDelay time.Duration
+	flag.StringVar(&addr, "addr", "127.0.0.1:8443", "The address the server binds to.")
+	flag.Var(&cas, "ca", "The ARN of a CA to serve, can be repeated. Defaults to "+defaultCAArn+".")
+	flag.Var(&disabledCAs, "disabled-ca", "The ARN of a CA with the DISABLED status, can be repeated.")
+	flag.Float64Var(&config.ThrottleRate, "throttle-rate", 0, "The fraction of the requests, between 0 and 1, that fail with a ThrottlingException.")
+	flag.DurationVar(&latency, "latency", 0, "The latency added to every request.")
+	flag.DurationVar(&issueDelay, "issue-delay", 0, "How long GetCertificate fails with a RequestInProgressException after a certificate is issued.")
+	flag.Parse()
+
+	if len(cas) == 0 {
+		cas = stringList{defaultCAArn}
+	}
+	config.DisabledCAs = disabledCAs
+	config.Latency = metav1.Duration{Duration: latency}
+	config.IssueDelay = metav1.Duration{Duration: issueDelay}
+
+	server, err := mockacmpca.NewServer(cas, config)
+	if err != nil {
+		log.Fatalf("failed to create the CAs: %v", err)
+	}
+
+	for _, ca := range cas {
+		fmt.Printf("serving CA %s\n", ca)
+	}
+	fmt.Printf("listening on http://%s, change the injected faults with PUT %s\n", addr, mockacmpca.ConfigPath)
+	log.Fatal(http.ListenAndServe(addr, server))
+}
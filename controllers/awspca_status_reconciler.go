@@ -19,8 +19,9 @@ import (
 	"context"
 	"fmt"
 
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/metrics"
 	"github.com/go-logr/logr"
-	api "github.com/awspca-issuer/api/v1alpha2"
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -42,49 +43,117 @@ func newAWSPCAStatusReconciler(r *AWSPCAIssuerReconciler,
 }
 
 func (r *AWSPCAStatusReconciler) Update(ctx context.Context,
-	status api.ConditionStatus, reason, message string, args ...interface{}) error {
+	status api.ConditionStatus, reason api.ConditionReason, message string, args ...interface{}) error {
 	completeMessage := fmt.Sprintf(message, args...)
-	r.setCondition(status, reason, completeMessage)
+	r.setCondition(api.ConditionReady, status, reason, completeMessage)
+	metrics.SetIssuerReady(r.issuer.Namespace, r.issuer.Name, status == api.ConditionTrue)
 
 	// Fire an Event to additionally inform users of the change
 	eventType := core.EventTypeNormal
 	if status == api.ConditionFalse {
 		eventType = core.EventTypeWarning
 	}
-	r.Recorder.Event(r.issuer, eventType, reason, completeMessage)
+	r.Recorder.Event(r.issuer, eventType, string(reason), completeMessage)
 
 	return r.Client.Status().Update(ctx, r.issuer)
 }
 
-func (r *AWSPCAStatusReconciler) UpdateNoError(ctx context.Context, status api.ConditionStatus, reason, message string, args ...interface{}) {
+func (r *AWSPCAStatusReconciler) UpdateNoError(ctx context.Context, status api.ConditionStatus, reason api.ConditionReason, message string, args ...interface{}) {
 	if err := r.Update(ctx, status, reason, message, args...); err != nil {
 		r.logger.Error(err, "failed to update", "status", status, "reason", reason)
 	}
 }
 
-// setCondition will set a 'condition' on the given api.AWSPCAIssuer resource.
+// readyConditions are the conditions of an issuer that Ready is the
+// conjunction of, in the order their failures are reported.
+var readyConditions = []api.ConditionType{
+	api.ConditionPolicyValid,
+	api.ConditionCredentialsValid,
+	api.ConditionCAReachable,
+	api.ConditionCAActive,
+}
+
+// UpdateReady sets the Ready condition of the issuer from its other
+// conditions and updates its status. Ready is True if all of
+// readyConditions are True and the private CA certificate has not expired.
+// Otherwise it is False, with the reason of the first False condition, or
+// of the first Unknown one if none is False.
+func (r *AWSPCAStatusReconciler) UpdateReady(ctx context.Context) error {
+	var failed *api.AWSPCAIssuerCondition
+	for _, t := range readyConditions {
+		c := r.condition(t)
+		switch {
+		case c == nil:
+			c = &api.AWSPCAIssuerCondition{Type: t, Status: api.ConditionUnknown, Reason: api.ReasonNotChecked}
+		case c.Status == api.ConditionTrue:
+			continue
+		}
+		if failed == nil || (failed.Status != api.ConditionFalse && c.Status == api.ConditionFalse) {
+			failed = c
+		}
+	}
+	if failed == nil {
+		if c := r.condition(api.ConditionCAExpiringSoon); c != nil && c.Reason == api.ReasonExpired {
+			failed = c
+		}
+	}
+
+	if failed != nil {
+		return r.Update(ctx, api.ConditionFalse, failed.Reason, "%s is %s: %s", failed.Type, failed.Status, failed.Message)
+	}
+	return r.Update(ctx, api.ConditionTrue, api.ReasonVerified, "AWSPCAIssuer verified and ready to sign certificates")
+}
+
+func (r *AWSPCAStatusReconciler) UpdateReadyNoError(ctx context.Context) {
+	if err := r.UpdateReady(ctx); err != nil {
+		r.logger.Error(err, "failed to update")
+	}
+}
+
+// setNotChecked sets the given conditions to Unknown because an earlier
+// check failed.
+func (r *AWSPCAStatusReconciler) setNotChecked(message string, conditionTypes ...api.ConditionType) {
+	for _, t := range conditionTypes {
+		r.setCondition(t, api.ConditionUnknown, api.ReasonNotChecked, message)
+	}
+}
+
+// condition returns the condition of the given type of the issuer, or nil.
+func (r *AWSPCAStatusReconciler) condition(conditionType api.ConditionType) *api.AWSPCAIssuerCondition {
+	for idx := range r.issuer.Status.Conditions {
+		if r.issuer.Status.Conditions[idx].Type == conditionType {
+			return &r.issuer.Status.Conditions[idx]
+		}
+	}
+	return nil
+}
+
+// setCondition will set a 'condition' of the given type on the given
+// api.AWSPCAIssuer resource, observed at the current generation of the
+// issuer.
 //
-// - If no condition of the same type already exists, the condition will be
-//   inserted with the LastTransitionTime set to the current time.
-// - If a condition of the same type and state already exists, the condition
-//   will be updated but the LastTransitionTime will not be modified.
-// - If a condition of the same type and different state already exists, the
-//   condition will be updated and the LastTransitionTime set to the current
-//   time.
-func (r *AWSPCAStatusReconciler) setCondition(status api.ConditionStatus, reason, message string) {
+//   - If no condition of the same type already exists, the condition will be
+//     inserted with the LastTransitionTime set to the current time.
+//   - If a condition of the same type and state already exists, the condition
+//     will be updated but the LastTransitionTime will not be modified.
+//   - If a condition of the same type and different state already exists, the
+//     condition will be updated and the LastTransitionTime set to the current
+//     time.
+func (r *AWSPCAStatusReconciler) setCondition(conditionType api.ConditionType, status api.ConditionStatus, reason api.ConditionReason, message string) {
 	now := meta.NewTime(r.Clock.Now())
 	c := api.AWSPCAIssuerCondition{
-		Type:               api.ConditionReady,
+		Type:               conditionType,
 		Status:             status,
 		Reason:             reason,
 		Message:            message,
 		LastTransitionTime: &now,
+		ObservedGeneration: r.issuer.Generation,
 	}
 
 	// Search through existing conditions
 	for idx, cond := range r.issuer.Status.Conditions {
 		// Skip unrelated conditions
-		if cond.Type != api.ConditionReady {
+		if cond.Type != conditionType {
 			continue
 		}
 
@@ -104,5 +173,5 @@ func (r *AWSPCAStatusReconciler) setCondition(status api.ConditionStatus, reason
 	// If we've not found an existing condition of this type, we simply insert
 	// the new condition into the slice.
 	r.issuer.Status.Conditions = append(r.issuer.Status.Conditions, c)
-	r.logger.Info("setting lastTransitionTime for AWSPCAIssuer condition", "condition", api.ConditionReady, "time", now.Time)
+	r.logger.Info("setting lastTransitionTime for AWSPCAIssuer condition", "condition", conditionType, "time", now.Time)
 }
@@ -0,0 +1,168 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/provisioners"
+	"github.com/awspca-issuer/tracing"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// AWSPCAIssuedCertificateReconciler revokes the issued certificates on
+// request and tracks their expiry.
+type AWSPCAIssuedCertificateReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Clock    clock.Clock
+	Recorder record.EventRecorder
+
+	// Context is the parent context of the reconciliations, canceled when
+	// the manager stops. Defaults to the background context.
+	Context context.Context
+	// AWSTimeout is the deadline of the AWS Private CA calls to revoke a
+	// certificate. Zero means no deadline.
+	AWSTimeout time.Duration
+	// MaxConcurrentReconciles is the maximum number of concurrent
+	// reconciliations. Defaults to 1.
+	MaxConcurrentReconciles int
+	// Namespaces are the namespaces watched by the controller, all the
+	// namespaces if empty.
+	Namespaces WatchedNamespaces
+}
+
+// +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuedcertificates,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuedcertificates/status,verbs=get;update;patch
+
+// Reconcile revokes the AWSPCAIssuedCertificate if spec.revocation is set,
+// and otherwise sets its state to Valid or Expired. Valid certificates are
+// requeued when they expire.
+func (r *AWSPCAIssuedCertificateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.StartReconcile(reconcileContext(r.Context), "AWSPCAIssuedCertificate", req.NamespacedName)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile implements Reconcile, within the span of the reconciliation.
+func (r *AWSPCAIssuedCertificateReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("awspcaissuedcertificate", req.NamespacedName)
+
+	if !r.Namespaces.Contains(req.Namespace) {
+		log.V(4).Info("ignoring AWSPCAIssuedCertificate outside the watched namespaces")
+		return ctrl.Result{}, nil
+	}
+
+	ic := new(api.AWSPCAIssuedCertificate)
+	if err := r.Client.Get(ctx, req.NamespacedName, ic); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if ic.Status.State == api.IssuedCertificateStateRevoked {
+		return ctrl.Result{}, nil
+	}
+	if ic.Spec.Revocation != nil {
+		return ctrl.Result{}, r.revoke(ctx, log, ic)
+	}
+
+	state := api.IssuedCertificateStateValid
+	result := ctrl.Result{}
+	if remaining := ic.Spec.NotAfter.Sub(r.Clock.Now()); remaining > 0 {
+		result.RequeueAfter = remaining
+	} else {
+		state = api.IssuedCertificateStateExpired
+	}
+	if ic.Status.State != state {
+		ic.Status.State = state
+		if err := r.Client.Status().Update(ctx, ic); err != nil {
+			log.Error(err, "failed to update AWSPCAIssuedCertificate status")
+			return ctrl.Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// revoke revokes the certificate in AWS Private CA with the provisioner of
+// its issuer. The certificate is identified by the private CA and serial
+// number recorded in its status by the controller, and the CA must be one of
+// the current or retired CAs of the issuer.
+func (r *AWSPCAIssuedCertificateReconciler) revoke(ctx context.Context, log logr.Logger, ic *api.AWSPCAIssuedCertificate) error {
+	reason := ic.Spec.Revocation.Reason
+	if reason == "" {
+		reason = api.RevocationReasonUnspecified
+	}
+
+	if ic.Status.CAArn == "" || ic.Status.SerialNumber == "" {
+		// Retrying would not help, the spec cannot be trusted to revoke it.
+		err := fmt.Errorf("the certificate was not recorded by the controller, it must be revoked in AWS Private CA")
+		r.revocationFailed(ctx, log, ic, err)
+		return nil
+	}
+
+	issuer := types.NamespacedName{Namespace: ic.Namespace, Name: ic.Spec.IssuerName}
+	var err error
+	if provisioner, ok := provisioners.Load(issuer); ok {
+		revokeCtx, cancel := awsContext(ctx, r.AWSTimeout)
+		err = provisioner.Revoke(revokeCtx, ic.Status.CAArn, ic.Status.SerialNumber, string(reason))
+		cancel()
+	} else {
+		err = fmt.Errorf("provisioner for AWSPCAIssuer %s not found", issuer)
+	}
+	if err != nil {
+		r.revocationFailed(ctx, log, ic, err)
+		return err
+	}
+
+	now := meta.NewTime(r.Clock.Now())
+	ic.Status.State = api.IssuedCertificateStateRevoked
+	ic.Status.RevocationTime = &now
+	ic.Status.Message = ""
+	r.Recorder.Eventf(ic, core.EventTypeNormal, string(api.ReasonRevoked), "Certificate revoked with reason %s", reason)
+	return r.Client.Status().Update(ctx, ic)
+}
+
+// revocationFailed records the error of a failed revocation in an event and
+// in the status of the AWSPCAIssuedCertificate.
+func (r *AWSPCAIssuedCertificateReconciler) revocationFailed(ctx context.Context, log logr.Logger, ic *api.AWSPCAIssuedCertificate, err error) {
+	log.Error(err, "failed to revoke certificate", "serial", ic.Spec.SerialNumber)
+	r.Recorder.Eventf(ic, core.EventTypeWarning, string(api.ReasonRevocationFailed), "Failed to revoke certificate: %v", err)
+	ic.Status.Message = err.Error()
+	if err := r.Client.Status().Update(ctx, ic); err != nil {
+		log.Error(err, "failed to update AWSPCAIssuedCertificate status")
+	}
+}
+
+// SetupWithManager initializes the AWSPCAIssuedCertificate controller into
+// the controller runtime.
+func (r *AWSPCAIssuedCertificateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.AWSPCAIssuedCertificate{}).
+		WithEventFilter(r.Namespaces.predicate()).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -16,146 +16,451 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/pem"
 	"fmt"
-	api "github.com/awspca-issuer/api/v1alpha2"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/acmpca"
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/metrics"
 	"github.com/awspca-issuer/provisioners"
+	"github.com/awspca-issuer/tracing"
 	"github.com/go-logr/logr"
 	core "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
 )
 
+// caRecheckDelay is the delay before the private CAs of an issuer are checked
+// again when its credentials were rejected or a CA could not be reached.
+const caRecheckDelay = 30 * time.Second
+
 type AWSPCAIssuerReconciler struct {
 	client.Client
 	Log      logr.Logger
 	Clock    clock.Clock
 	Recorder record.EventRecorder
+
+	// CAExpiryWarningThreshold is the default remaining validity of a private
+	// CA certificate below which the CAExpiringSoon condition is set.
+	CAExpiryWarningThreshold time.Duration
+	// CAExpiryCheckInterval is how often ready issuers are requeued to check
+	// the expiry of their private CA. Zero disables the periodic check.
+	CAExpiryCheckInterval time.Duration
+
+	// Context is the parent context of the reconciliations, canceled when
+	// the manager stops. Defaults to the background context.
+	Context context.Context
+	// AWSTimeout is the deadline of the AWS Private CA calls to check the
+	// private CAs of an issuer. Zero means no deadline.
+	AWSTimeout time.Duration
+	// MaxConcurrentReconciles is the maximum number of concurrent
+	// reconciliations. Defaults to 1.
+	MaxConcurrentReconciles int
+	// Namespaces are the namespaces watched by the controller, all the
+	// namespaces if empty.
+	Namespaces WatchedNamespaces
+	// AllowAmbientCredentials lets the issuers without a secret use the
+	// default AWS credential chain of the controller.
+	AllowAmbientCredentials bool
 }
 
 // +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile will read and validate the AWSPCAIssuer resources, it will set the
 // status condition ready to true if everything is right.
 func (r *AWSPCAIssuerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
+	ctx, span := tracing.StartReconcile(reconcileContext(r.Context), "AWSPCAIssuer", req.NamespacedName)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile implements Reconcile, within the span of the reconciliation.
+func (r *AWSPCAIssuerReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("awspcaissuer", req.NamespacedName)
 
+	if !r.Namespaces.Contains(req.Namespace) {
+		log.V(4).Info("ignoring AWSPCAIssuer outside the watched namespaces")
+		return ctrl.Result{}, nil
+	}
+
 	iss := new(api.AWSPCAIssuer)
 	if err := r.Client.Get(ctx, req.NamespacedName, iss); err != nil {
 		log.Error(err, "failed to retrieve AWSPCAIssuer resource")
+		if apierrors.IsNotFound(err) {
+			metrics.DeleteIssuer(req.Namespace, req.Name)
+			provisioners.Delete(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	// Delete the trust bundle ConfigMaps with the issuer, or when the trust
+	// bundle is removed from its spec.
+	if hasFinalizer(iss, api.TrustBundleFinalizer) && (iss.DeletionTimestamp != nil || iss.Spec.TrustBundle == nil) {
+		if err := r.deleteTrustBundles(ctx, iss, nil); err != nil {
+			log.Error(err, "failed to delete trust bundle ConfigMaps")
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(iss, api.TrustBundleFinalizer)
+		if err := r.Client.Update(ctx, iss); err != nil {
+			log.Error(err, "failed to remove finalizer from AWSPCAIssuer resource")
+			return ctrl.Result{}, err
+		}
+	}
+	if iss.DeletionTimestamp != nil {
+		metrics.DeleteIssuer(req.Namespace, req.Name)
+		provisioners.Delete(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+	if iss.Spec.TrustBundle != nil && !hasFinalizer(iss, api.TrustBundleFinalizer) {
+		controllerutil.AddFinalizer(iss, api.TrustBundleFinalizer)
+		if err := r.Client.Update(ctx, iss); err != nil {
+			log.Error(err, "failed to add finalizer to AWSPCAIssuer resource")
+			return ctrl.Result{}, err
+		}
+	}
+
 	statusReconciler := newAWSPCAStatusReconciler(r, iss, log)
 	if err := validateAWSPCAIssuerSpec(iss.Spec); err != nil {
 		log.Error(err, "failed to validate AWSPCAIssuer resource")
-		statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "Validation", "Failed to validate resource: %v", err)
+		statusReconciler.setCondition(api.ConditionPolicyValid, api.ConditionFalse, api.ReasonValidation, fmt.Sprintf("Failed to validate resource: %v", err))
+		statusReconciler.setNotChecked("The spec of the AWSPCAIssuer is invalid",
+			api.ConditionCredentialsValid, api.ConditionCAReachable, api.ConditionCAActive, api.ConditionCAExpiringSoon)
+		statusReconciler.UpdateReadyNoError(ctx)
 		return ctrl.Result{}, err
 	}
 
 	// Initialize and store the provisioner
-
-	// AWS access key, secret key, region, and private CA ARN are all stored as secrets
-	var secret core.Secret
-	var ok bool
-	var accessKey []byte
-	var secretKey []byte
-	var region []byte
-	var arn []byte
-
-	secretNamespaceName := types.NamespacedName{
-		Namespace: req.Namespace,
-		Name:      iss.Spec.Provisioner.Name,
-	}
-
-	if err := r.Client.Get(ctx, secretNamespaceName, &secret); err != nil {
-		log.Error(err, "failed to retrieve AWS secrets", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
-		if apierrors.IsNotFound(err) {
-			statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "NotFound", "Failed to retrieve AWS secrets: %v", err)
+	p, err := provisioners.FromIssuer(ctx, r.Client, iss, r.AllowAmbientCredentials)
+	if err != nil {
+		log.Error(err, "failed to initialize provisioner")
+		reason := api.ReasonError
+		if ierr, ok := err.(*provisioners.IssuerError); ok {
+			reason = ierr.Reason
+		}
+		if reason == api.ReasonValidation {
+			statusReconciler.setCondition(api.ConditionPolicyValid, api.ConditionFalse, reason, err.Error())
+			statusReconciler.setNotChecked("The spec of the AWSPCAIssuer is invalid", api.ConditionCredentialsValid)
 		} else {
-			statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "Error", "Failed to retrieve AWS secrets: %v", err)
+			statusReconciler.setCondition(api.ConditionPolicyValid, api.ConditionTrue, api.ReasonValid, "The spec of the AWSPCAIssuer is valid")
+			statusReconciler.setCondition(api.ConditionCredentialsValid, api.ConditionFalse, reason, err.Error())
 		}
+		statusReconciler.setNotChecked("The AWS credentials of the AWSPCAIssuer could not be loaded",
+			api.ConditionCAReachable, api.ConditionCAActive, api.ConditionCAExpiringSoon)
+		statusReconciler.UpdateReadyNoError(ctx)
 		return ctrl.Result{}, err
 	}
+	statusReconciler.setCondition(api.ConditionPolicyValid, api.ConditionTrue, api.ReasonValid, "The spec of the AWSPCAIssuer is valid")
 
-	accessKey, ok = secret.Data[iss.Spec.Provisioner.AccessKeyRef.Key]
-
-	if !ok {
-		err := fmt.Errorf("secret %s does not contain key %s", secret.Name, iss.Spec.Provisioner.AccessKeyRef.Key)
-		log.Error(err, "failed to retrieve AWS access key from secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
-		statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "NotFound", "Failed to retrieve AWS access key from secret: %v", err)
-		return ctrl.Result{}, err
+	issNamespaceName := types.NamespacedName{
+		Namespace: req.Namespace,
+		Name:      req.Name,
 	}
 
-	secretKey, ok = secret.Data[iss.Spec.Provisioner.SecretKeyRef.Key]
+	provisioners.Store(issNamespaceName, p)
+
+	awsCtx, cancel := awsContext(ctx, r.AWSTimeout)
+	err = r.checkCertificateAuthorities(awsCtx, statusReconciler, p)
+	cancel()
+	provisioners.SetVerification(issNamespaceName, r.Clock.Now(), err)
 
-	if !ok {
-		err := fmt.Errorf("secret %s does not contain key %s", secret.Name, iss.Spec.Provisioner.SecretKeyRef.Key)
-		log.Error(err, "failed to retrieve AWS secret key from secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
-		statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "NotFound", "Failed to retrieve AWS secret key from secret: %v", err)
+	if err := statusReconciler.UpdateReady(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	region, ok = secret.Data[iss.Spec.Provisioner.RegionRef.Key]
+	if iss.Spec.TrustBundle != nil {
+		if err := r.syncTrustBundle(ctx, iss); err != nil {
+			log.Error(err, "failed to distribute trust bundle")
+			r.Recorder.Event(iss, core.EventTypeWarning, string(api.ReasonTrustBundleFailed), err.Error())
+			return ctrl.Result{}, err
+		}
+	}
 
-	if !ok {
-		err := fmt.Errorf("secret %s does not contain key %s", secret.Name, iss.Spec.Provisioner.RegionRef.Key)
-		log.Error(err, "failed to retrieve AWS region from secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
-		statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "NotFound", "Failed to retrieve AWS region from secret: %v", err)
+	// The private CAs are checked again with backoff if none could be
+	// described, and after caRecheckDelay if the credentials were rejected
+	// or some CAs could not be reached, rather than at the next expiry check.
+	if err != nil {
+		log.Error(err, "failed to check the AWS Private CAs")
 		return ctrl.Result{}, err
 	}
+	for _, t := range []api.ConditionType{api.ConditionCredentialsValid, api.ConditionCAReachable} {
+		if c := statusReconciler.condition(t); c == nil || c.Status != api.ConditionTrue {
+			if r.CAExpiryCheckInterval > 0 && r.CAExpiryCheckInterval < caRecheckDelay {
+				break
+			}
+			return ctrl.Result{RequeueAfter: caRecheckDelay}, nil
+		}
+	}
+	return ctrl.Result{RequeueAfter: r.CAExpiryCheckInterval}, nil
+}
 
-	arn, ok = secret.Data[iss.Spec.Provisioner.ArnRef.Key]
+// checkCertificateAuthorities describes the private CAs of the issuer to
+// report their health in its status, and sets the CredentialsValid,
+// CAReachable, CAActive and CAExpiringSoon conditions. CAExpiringSoon is set
+// from the certificate of the CA, not draining, that expires first. It also
+// collects the certificates of all the CAs in the CA bundle of the issuer.
+// It returns an error if none of the CAs could be described, e.g. because
+// AWS cannot be reached.
+func (r *AWSPCAIssuerReconciler) checkCertificateAuthorities(ctx context.Context, sr *AWSPCAStatusReconciler, p *provisioners.AWSPCAProvisioner) error {
+	var statuses []api.CertificateAuthorityStatus
+	var verifyErr error
+	described := false
+	credentialsRejected := true
+	var active, inactive *api.CertificateAuthorityStatus
+	var notAfter *time.Time
+	checkFailure := "AWS Private CA has no certificate installed"
 
-	if !ok {
-		err := fmt.Errorf("secret %s does not contain key %s", secret.Name, iss.Spec.Provisioner.ArnRef.Key)
-		log.Error(err, "failed to retrieve AWS Private CA ARN from secret", "namespace", secretNamespaceName.Namespace, "name", secretNamespaceName.Name)
-		statusReconciler.UpdateNoError(ctx, api.ConditionFalse, "NotFound", "Failed to retrieve AWS Private CA ARN from secret: %v", err)
-		return ctrl.Result{}, err
+	bundle := newCABundle()
+	bundleComplete := true
+
+	for _, ca := range p.CertificateAuthorities() {
+		status := api.CertificateAuthorityStatus{Arn: ca.Arn}
+		desc, err := p.DescribeCertificateAuthority(ctx, ca)
+		switch {
+		case err != nil:
+			sr.logger.Error(err, "failed to describe AWS Private CA", "arn", ca.Arn)
+			checkFailure = fmt.Sprintf("Failed to describe AWS Private CA: %v", err)
+			status.Ready, status.Reason, status.Message = api.ConditionUnknown, api.ReasonCheckFailed, checkFailure
+			bundleComplete = false
+			verifyErr = err
+			credentialsRejected = credentialsRejected && provisioners.IsCredentialsError(err)
+		case aws.StringValue(desc.Status) != acmpca.CertificateAuthorityStatusActive:
+			status.Ready, status.Reason = api.ConditionFalse, api.ReasonNotActive
+			status.Message = fmt.Sprintf("AWS Private CA status is %s", aws.StringValue(desc.Status))
+		default:
+			status.Ready, status.Reason, status.Message = api.ConditionTrue, api.ReasonActive, "AWS Private CA is active"
+		}
+
+		if err == nil && desc.NotAfter != nil {
+			t := meta.NewTime(*desc.NotAfter)
+			status.NotAfter = &t
+			metrics.SetCAExpiry(sr.issuer.Namespace, sr.issuer.Name, ca.Arn, *desc.NotAfter)
+			if !ca.Draining && (notAfter == nil || desc.NotAfter.Before(*notAfter)) {
+				notAfter = desc.NotAfter
+			}
+
+			caPem, err := p.GetCertificateAuthorityCertificate(ctx, ca)
+			if err != nil {
+				sr.logger.Error(err, "failed to retrieve AWS Private CA certificate", "arn", ca.Arn)
+				bundleComplete = false
+			} else if err := bundle.add(caPem); err != nil {
+				sr.logger.Error(err, "failed to parse AWS Private CA certificate", "arn", ca.Arn)
+				bundleComplete = false
+			}
+		}
+		if err == nil {
+			described = true
+			if !ca.Draining && status.Ready == api.ConditionTrue && active == nil {
+				active = &status
+			} else if !ca.Draining && status.Ready == api.ConditionFalse && inactive == nil {
+				inactive = &status
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sr.issuer.Status.RetiredCertificateAuthorities = provisioners.RetiredCAs(sr.issuer.Status, p.CertificateAuthorities())
+	sr.issuer.Status.CertificateAuthorities = statuses
+	arns := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		arns = append(arns, status.Arn)
+	}
+	metrics.RetainCAs(sr.issuer.Namespace, sr.issuer.Name, arns)
+	if described {
+		verifyErr = nil
+	}
+
+	switch {
+	case described:
+		sr.setCondition(api.ConditionCredentialsValid, api.ConditionTrue, api.ReasonAccepted, "AWS credentials accepted")
+		sr.setCondition(api.ConditionCAReachable, api.ConditionTrue, api.ReasonReachable, "AWS Private CA described")
+	case verifyErr == nil:
+		// The issuer has no private CA to describe.
+		sr.setNotChecked(checkFailure, api.ConditionCredentialsValid, api.ConditionCAReachable)
+	case credentialsRejected:
+		sr.setCondition(api.ConditionCredentialsValid, api.ConditionFalse, api.ReasonRejected, checkFailure)
+		sr.setCondition(api.ConditionCAReachable, api.ConditionFalse, api.ReasonUnreachable, checkFailure)
+	default:
+		sr.setCondition(api.ConditionCredentialsValid, api.ConditionUnknown, api.ReasonCheckFailed, checkFailure)
+		sr.setCondition(api.ConditionCAReachable, api.ConditionFalse, api.ReasonUnreachable, checkFailure)
+	}
+	switch {
+	case active != nil:
+		sr.setCondition(api.ConditionCAActive, api.ConditionTrue, active.Reason, active.Message)
+	case inactive != nil:
+		sr.setCondition(api.ConditionCAActive, api.ConditionFalse, inactive.Reason, inactive.Message)
+	default:
+		sr.setCondition(api.ConditionCAActive, api.ConditionUnknown, api.ReasonCheckFailed, checkFailure)
 	}
 
-	p := provisioners.NewProvisioner(string(accessKey), string(secretKey),
-		string(region), string(arn))
+	// A partial bundle would break the trust in the certificates issued by
+	// the missing CAs, keep the previous one until all CAs can be read.
+	if bundleComplete {
+		sr.issuer.Status.CABundle = bundle.bytes()
+	}
 
-	issNamespaceName := types.NamespacedName{
-		Namespace: req.Namespace,
-		Name:      req.Name,
+	if notAfter == nil {
+		sr.setCondition(api.ConditionCAExpiringSoon, api.ConditionUnknown, api.ReasonCheckFailed, checkFailure)
+		return verifyErr
 	}
+	r.checkCAExpiry(sr, *notAfter)
+	return verifyErr
+}
 
-	provisioners.Store(issNamespaceName, p)
+// checkCAExpiry sets the CAExpiringSoon condition of the issuer from the
+// NotAfter time of its private CA certificate, and fires a Warning event if
+// the CA is about to expire or has already expired.
+func (r *AWSPCAIssuerReconciler) checkCAExpiry(sr *AWSPCAStatusReconciler, notAfter time.Time) {
+	threshold := r.CAExpiryWarningThreshold
+	if sr.issuer.Spec.CAExpiryWarningThreshold != nil {
+		threshold = sr.issuer.Spec.CAExpiryWarningThreshold.Duration
+	}
 
-	return ctrl.Result{}, statusReconciler.Update(ctx, api.ConditionTrue, "Verified", "AWSPCAIssuer verified and ready to sign certificates")
+	remaining := notAfter.Sub(r.Clock.Now())
+	switch {
+	case remaining <= 0:
+		message := fmt.Sprintf("AWS Private CA certificate expired at %s", notAfter.UTC().Format(time.RFC3339))
+		sr.setCondition(api.ConditionCAExpiringSoon, api.ConditionTrue, api.ReasonExpired, message)
+		r.Recorder.Event(sr.issuer, core.EventTypeWarning, string(api.ReasonCAExpired), message)
+	case remaining < threshold:
+		message := fmt.Sprintf("AWS Private CA certificate expires at %s, in %s; issued certificates cannot be valid beyond that time",
+			notAfter.UTC().Format(time.RFC3339), remaining.Round(time.Minute))
+		sr.setCondition(api.ConditionCAExpiringSoon, api.ConditionTrue, api.ReasonExpiringSoon, message)
+		r.Recorder.Event(sr.issuer, core.EventTypeWarning, string(api.ReasonCAExpiringSoon), message)
+	default:
+		sr.setCondition(api.ConditionCAExpiringSoon, api.ConditionFalse, api.ReasonValid,
+			fmt.Sprintf("AWS Private CA certificate expires at %s", notAfter.UTC().Format(time.RFC3339)))
+	}
 }
 
 // SetupWithManager initializes the AWSPCAIssuer controller into the controller
 // runtime.
 func (r *AWSPCAIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&api.AWSPCAIssuer{}).
-		Complete(r)
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Watches(&source.Kind{Type: &core.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapSecret),
+		})
+	// Namespaces are cluster scoped, they can only be watched with a cache
+	// of all the namespaces.
+	if r.Namespaces.All() {
+		b = b.Watches(&source.Kind{Type: &core.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapTrustBundleNamespace),
+		})
+	}
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+	return c.Watch(&source.Kind{Type: &core.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(mapTrustBundleConfigMap),
+	}, trustBundlePredicate())
+}
+
+// mapSecret enqueues the issuers referencing a secret when it changes, so
+// that its AWS credentials are used as soon as they are created or rotated.
+func (r *AWSPCAIssuerReconciler) mapSecret(o handler.MapObject) []ctrl.Request {
+	var list api.AWSPCAIssuerList
+	if err := r.Client.List(context.Background(), &list, client.InNamespace(o.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "failed to list AWSPCAIssuer resources")
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for _, iss := range list.Items {
+		if iss.Spec.SecretRef != nil && iss.Spec.SecretRef.Name == o.Meta.GetName() {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: iss.Namespace, Name: iss.Name},
+			})
+		}
+	}
+	return requests
 }
 
 func validateAWSPCAIssuerSpec(s api.AWSPCAIssuerSpec) error {
+	for i, ca := range s.CertificateAuthorities {
+		if ca.Arn == "" {
+			return fmt.Errorf("spec.certificateAuthorities[%d].arn cannot be empty", i)
+		}
+	}
+
+	if s.SecretRef == nil {
+		if s.Arn == "" && len(s.CertificateAuthorities) == 0 {
+			return fmt.Errorf("one of spec.arn or spec.certificateAuthorities must be set if spec.secretRef is not set")
+		}
+		return nil
+	}
+
 	switch {
-	case s.Provisioner.Name == "":
-		return fmt.Errorf("spec.provisioner.name cannot be empty")
-	case s.Provisioner.AccessKeyRef.Key == "":
-		return fmt.Errorf("spec.provisioner.accesskeyRef.key cannot be empty")
-	case s.Provisioner.SecretKeyRef.Key == "":
-		return fmt.Errorf("spec.provisioner.secretkeyRef.key cannot be empty")
-	case s.Provisioner.RegionRef.Key == "":
-		return fmt.Errorf("spec.provisioner.regionRef.key cannot be empty")
-	case s.Provisioner.ArnRef.Key == "":
-		return fmt.Errorf("spec.provisioner.arnRef.key cannot be empty")
+	case s.SecretRef.Name == "":
+		return fmt.Errorf("spec.secretRef.name cannot be empty")
+	case s.SecretRef.AccessKeyRef.Key == "":
+		return fmt.Errorf("spec.secretRef.accesskeyRef.key cannot be empty")
+	case s.SecretRef.SecretKeyRef.Key == "":
+		return fmt.Errorf("spec.secretRef.secretkeyRef.key cannot be empty")
+	case s.Arn == "" && s.SecretRef.ArnRef.Key == "" && len(s.CertificateAuthorities) == 0:
+		return fmt.Errorf("one of spec.arn or spec.secretRef.arnRef.key must be set")
 	default:
 		return nil
 	}
 }
+
+// caBundle collects PEM encoded certificates, skipping the duplicates such as
+// a root CA shared by several subordinate CAs.
+type caBundle struct {
+	buf  bytes.Buffer
+	seen map[string]bool
+}
+
+func newCABundle() *caBundle {
+	return &caBundle{seen: make(map[string]bool)}
+}
+
+// add appends the certificates of the given PEM data to the bundle.
+func (b *caBundle) add(data []byte) error {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block of type %s", block.Type)
+		}
+		if !b.seen[string(block.Bytes)] {
+			b.seen[string(block.Bytes)] = true
+			if err := pem.Encode(&b.buf, block); err != nil {
+				return err
+			}
+		}
+		data = rest
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		return fmt.Errorf("invalid PEM data")
+	}
+	return nil
+}
+
+// bytes returns the PEM encoded bundle, or nil if it is empty.
+func (b *caBundle) bytes() []byte {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	return b.buf.Bytes()
+}
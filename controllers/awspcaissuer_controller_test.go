@@ -0,0 +1,277 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/mockacmpca"
+	"github.com/awspca-issuer/provisioners"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testNamespace = "default"
+
+// newSecret returns a secret holding the given AWS access key.
+func newSecret(name, accessKey string) *core.Secret {
+	return &core.Secret{
+		ObjectMeta: meta.ObjectMeta{Namespace: testNamespace, Name: name},
+		Data: map[string][]byte{
+			"accesskey": []byte(accessKey),
+			"secretkey": []byte("secret"),
+		},
+	}
+}
+
+// newIssuer returns an issuer of the given private CA, with the AWS
+// credentials of the secret of the same name.
+func newIssuer(name, caArn string) *api.AWSPCAIssuer {
+	return &api.AWSPCAIssuer{
+		ObjectMeta: meta.ObjectMeta{Namespace: testNamespace, Name: name},
+		Spec: api.AWSPCAIssuerSpec{
+			Arn:    caArn,
+			Region: testRegion,
+			SecretRef: &api.AWSCredentialsSecretReference{
+				Name:         name,
+				AccessKeyRef: api.SecretKeySelector{Key: "accesskey"},
+				SecretKeyRef: api.SecretKeySelector{Key: "secretkey"},
+			},
+		},
+	}
+}
+
+// createReadyIssuer creates an issuer of the given private CA with its
+// secret, and waits for it to be ready.
+func createReadyIssuer(name, caArn string) *api.AWSPCAIssuer {
+	ctx := context.Background()
+	Expect(k8sClient.Create(ctx, newSecret(name, "access"))).To(Succeed())
+	iss := newIssuer(name, caArn)
+	Expect(k8sClient.Create(ctx, iss)).To(Succeed())
+	Eventually(issuerReady(name)).Should(Equal("True/Verified"))
+
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, iss)).To(Succeed())
+	return iss
+}
+
+// getIssuer returns the issuer with the given name, or nil if it can not be
+// retrieved.
+func getIssuer(name string) *api.AWSPCAIssuer {
+	iss := new(api.AWSPCAIssuer)
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, iss); err != nil {
+		return nil
+	}
+	return iss
+}
+
+// checkIssuer triggers a reconciliation of the issuer with the given name,
+// as its periodic check would, by changing an annotation.
+func checkIssuer(name string) {
+	Eventually(func() error {
+		iss := getIssuer(name)
+		if iss == nil {
+			return fmt.Errorf("AWSPCAIssuer %s not found", name)
+		}
+		if iss.Annotations == nil {
+			iss.Annotations = make(map[string]string)
+		}
+		iss.Annotations["test.certmanager.awspca/checked"] = time.Now().Format(time.RFC3339Nano)
+		return k8sClient.Update(context.Background(), iss)
+	}).Should(Succeed())
+}
+
+// issuerCondition returns a function returning the status and reason of the
+// condition of the given type of the issuer with the given name, as
+// "<status>/<reason>".
+func issuerCondition(name string, conditionType api.ConditionType) func() string {
+	return func() string {
+		iss := getIssuer(name)
+		if iss == nil {
+			return ""
+		}
+		for _, c := range iss.Status.Conditions {
+			if c.Type == conditionType {
+				return string(c.Status) + "/" + string(c.Reason)
+			}
+		}
+		return ""
+	}
+}
+
+// issuerReady returns a function returning the status and reason of the
+// Ready condition of the issuer with the given name, as "<status>/<reason>".
+func issuerReady(name string) func() string {
+	return issuerCondition(name, api.ConditionReady)
+}
+
+// caReady returns a function returning the readiness and reason of the
+// private CA of the issuer with the given name, as "<ready>/<reason>".
+func caReady(name string) func() string {
+	return func() string {
+		iss := getIssuer(name)
+		if iss == nil || len(iss.Status.CertificateAuthorities) == 0 {
+			return ""
+		}
+		status := iss.Status.CertificateAuthorities[0]
+		return string(status.Ready) + "/" + string(status.Reason)
+	}
+}
+
+var _ = Describe("AWSPCAIssuerReconciler", func() {
+	ctx := context.Background()
+
+	AfterEach(func() {
+		pca.SetConfig(mockacmpca.Config{})
+	})
+
+	It("becomes ready when its secret is created", func() {
+		Expect(k8sClient.Create(ctx, newIssuer("issuer-secret", testCAArn))).To(Succeed())
+		Eventually(issuerReady("issuer-secret")).Should(Equal("False/NotFound"))
+		Expect(issuerCondition("issuer-secret", api.ConditionCredentialsValid)()).To(Equal("False/NotFound"))
+		Expect(issuerCondition("issuer-secret", api.ConditionCAActive)()).To(Equal("Unknown/NotChecked"))
+
+		Expect(k8sClient.Create(ctx, newSecret("issuer-secret", "access"))).To(Succeed())
+		Eventually(issuerReady("issuer-secret")).Should(Equal("True/Verified"))
+		Eventually(caReady("issuer-secret")).Should(Equal("True/Active"))
+
+		iss := getIssuer("issuer-secret")
+		Expect(iss.Status.CABundle).ToNot(BeEmpty())
+		Expect(iss.Status.CertificateAuthorities[0].NotAfter).ToNot(BeNil())
+		for _, c := range iss.Status.Conditions {
+			Expect(c.ObservedGeneration).To(Equal(iss.Generation))
+		}
+		for _, t := range []api.ConditionType{api.ConditionPolicyValid, api.ConditionCredentialsValid, api.ConditionCAReachable, api.ConditionCAActive} {
+			Expect(issuerCondition("issuer-secret", t)()).To(HavePrefix("True/"))
+		}
+		Expect(issuerCondition("issuer-secret", api.ConditionCAExpiringSoon)()).To(Equal("False/Valid"))
+
+		var verification *provisioners.Verification
+		for _, info := range provisioners.List() {
+			if info.Namespace == testNamespace && info.Name == "issuer-secret" {
+				verification = info.Verification
+			}
+		}
+		Expect(verification).ToNot(BeNil())
+		Expect(verification.Error).To(BeEmpty())
+	})
+
+	It("is not ready with an invalid spec", func() {
+		iss := newIssuer("issuer-invalid", "")
+		iss.Spec.SecretRef = nil
+		Expect(k8sClient.Create(ctx, iss)).To(Succeed())
+		Eventually(issuerReady("issuer-invalid")).Should(Equal("False/Validation"))
+		Expect(issuerCondition("issuer-invalid", api.ConditionPolicyValid)()).To(Equal("False/Validation"))
+	})
+
+	It("is not ready when its secret loses the access key", func() {
+		createReadyIssuer("issuer-key", testCAArn)
+
+		secret := new(core.Secret)
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "issuer-key"}, secret)).To(Succeed())
+		delete(secret.Data, "accesskey")
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		Eventually(issuerReady("issuer-key")).Should(Equal("False/NotFound"))
+
+		secret.Data["accesskey"] = []byte("access")
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		Eventually(issuerReady("issuer-key")).Should(Equal("True/Verified"))
+	})
+
+	It("uses the rotated credentials of its secret", func() {
+		pca.SetConfig(mockacmpca.Config{AccessKeyID: "access"})
+		createReadyIssuer("issuer-rotation", testCAArn)
+		Eventually(caReady("issuer-rotation")).Should(Equal("True/Active"))
+
+		// The old access key is revoked by AWS before the secret is
+		// updated.
+		pca.SetConfig(mockacmpca.Config{AccessKeyID: "rotated"})
+		checkIssuer("issuer-rotation")
+		Eventually(caReady("issuer-rotation")).Should(Equal("Unknown/CheckFailed"))
+		Eventually(issuerReady("issuer-rotation")).Should(Equal("False/Rejected"))
+		Expect(issuerCondition("issuer-rotation", api.ConditionCAReachable)()).To(Equal("False/Unreachable"))
+
+		secret := new(core.Secret)
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "issuer-rotation"}, secret)).To(Succeed())
+		secret.Data["accesskey"] = []byte("rotated")
+		Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+		Eventually(caReady("issuer-rotation")).Should(Equal("True/Active"))
+		Eventually(issuerReady("issuer-rotation")).Should(Equal("True/Verified"))
+	})
+
+	It("checks its private CAs again when AWS rejected them", func() {
+		createReadyIssuer("issuer-recheck", testCAArn)
+
+		pca.SetConfig(mockacmpca.Config{AccessKeyID: "revoked"})
+		checkIssuer("issuer-recheck")
+		Eventually(issuerReady("issuer-recheck")).Should(Equal("False/Rejected"))
+
+		// The check is retried with backoff, without waiting for the
+		// CAExpiryCheckInterval of an hour or a change of the issuer.
+		pca.SetConfig(mockacmpca.Config{})
+		Eventually(issuerReady("issuer-recheck")).Should(Equal("True/Verified"))
+	})
+
+	It("reports a disabled private CA", func() {
+		createReadyIssuer("issuer-disabled", otherCAArn)
+		Eventually(caReady("issuer-disabled")).Should(Equal("True/Active"))
+
+		pca.SetConfig(mockacmpca.Config{DisabledCAs: []string{otherCAArn}})
+		checkIssuer("issuer-disabled")
+		Eventually(caReady("issuer-disabled")).Should(Equal("False/NotActive"))
+		Eventually(issuerReady("issuer-disabled")).Should(Equal("False/NotActive"))
+		Expect(issuerCondition("issuer-disabled", api.ConditionCAReachable)()).To(Equal("True/Reachable"))
+
+		pca.SetConfig(mockacmpca.Config{})
+		checkIssuer("issuer-disabled")
+		Eventually(caReady("issuer-disabled")).Should(Equal("True/Active"))
+		Eventually(issuerReady("issuer-disabled")).Should(Equal("True/Verified"))
+	})
+
+	It("only writes its trust bundle to the namespaces that opt in", func() {
+		Expect(k8sClient.Create(ctx, &core.Namespace{ObjectMeta: meta.ObjectMeta{
+			Name:   "trust-bundle-opt-in",
+			Labels: map[string]string{trustBundleNamespaceLabel: "true"},
+		}})).To(Succeed())
+		Expect(k8sClient.Create(ctx, &core.Namespace{ObjectMeta: meta.ObjectMeta{
+			Name: "trust-bundle-other-tenant",
+		}})).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, newSecret("issuer-trust-bundle", "access"))).To(Succeed())
+		iss := newIssuer("issuer-trust-bundle", testCAArn)
+		iss.Spec.TrustBundle = &api.TrustBundle{
+			ConfigMapName: "ca-bundle",
+			Key:           "ca.crt",
+			Namespaces:    []string{testNamespace, "trust-bundle-opt-in", "trust-bundle-other-tenant"},
+		}
+		Expect(k8sClient.Create(ctx, iss)).To(Succeed())
+
+		configMap := func(namespace string) func() error {
+			return func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "ca-bundle"}, new(core.ConfigMap))
+			}
+		}
+		Eventually(configMap(testNamespace)).Should(Succeed())
+		Eventually(configMap("trust-bundle-opt-in")).Should(Succeed())
+		Consistently(configMap("trust-bundle-other-tenant"), 3*time.Second).ShouldNot(Succeed())
+	})
+})
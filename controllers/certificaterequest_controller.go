@@ -18,38 +18,88 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
+	api "github.com/awspca-issuer/api/v1beta1"
+	"github.com/awspca-issuer/audit"
+	cmapi "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/metrics"
+	"github.com/awspca-issuer/provisioners"
+	"github.com/awspca-issuer/tracing"
 	"github.com/go-logr/logr"
-	apiutil "github.com/jetstack/cert-manager/pkg/api/util"
-	cmapi "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
 	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
-	api "github.com/awspca-issuer/api/v1alpha2"
-	"github.com/awspca-issuer/provisioners"
 	core "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
+// certificateNameAnnotation is set by cert-manager on the CertificateRequests
+// created for a Certificate.
+const certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+// pendingRequeueDelay is the delay before a CertificateRequest whose
+// certificate is still being issued by AWS Private CA is checked again.
+const pendingRequeueDelay = 30 * time.Second
+
 // CertificateRequestReconciler reconciles a AWSPCAIssuer object.
 type CertificateRequestReconciler struct {
 	client.Client
 	Log      logr.Logger
 	Recorder record.EventRecorder
+
+	// DisableApprovalCheck signs CertificateRequests without waiting for
+	// them to be approved, for versions of cert-manager older than v1.3
+	// which do not support the approval flow.
+	DisableApprovalCheck bool
+
+	// Audit receives a record of every sign attempt, if not nil.
+	Audit audit.Sink
+
+	// Context is the parent context of the reconciliations, canceled when
+	// the manager stops. Defaults to the background context.
+	Context context.Context
+	// AWSTimeout is the deadline of the AWS Private CA calls to sign a
+	// CertificateRequest, including the wait for the certificate to be
+	// issued. Zero means no deadline.
+	AWSTimeout time.Duration
+	// MaxConcurrentReconciles is the maximum number of concurrent
+	// reconciliations. Defaults to 1.
+	MaxConcurrentReconciles int
+	// Namespaces are the namespaces watched by the controller, all the
+	// namespaces if empty.
+	Namespaces WatchedNamespaces
 }
 
-// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaauditrecords,verbs=create
+// +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuedcertificates,verbs=get;create
+// +kubebuilder:rbac:groups=certmanager.awspca,resources=awspcaissuedcertificates/status,verbs=patch
 
 // Reconcile will read and validate a AWSPCAIssuer resource associated to the
 // CertificateRequest resource, and it will sign the CertificateRequest with the
 // provisioner in the AWSPCAIssuer.
 func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	ctx := context.Background()
+	ctx, span := tracing.StartReconcile(reconcileContext(r.Context), "CertificateRequest", req.NamespacedName)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+// reconcile implements Reconcile, within the span of the reconciliation.
+func (r *CertificateRequestReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("certificaterequest", req.NamespacedName)
 
+	if !r.Namespaces.Contains(req.Namespace) {
+		log.V(4).Info("ignoring CertificateRequest outside the watched namespaces")
+		return ctrl.Result{}, nil
+	}
+
 	// Fetch the CertificateRequest resource being reconciled.
 	// Just ignore the request if the certificate request has been deleted.
 	cr := new(cmapi.CertificateRequest)
@@ -61,6 +111,7 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 		log.Error(err, "failed to retrieve CertificateRequest resource")
 		return ctrl.Result{}, err
 	}
+	tracing.SetAttributes(ctx, tracing.CertificateRequestUIDKey.String(string(cr.UID)))
 
 	// Check the CertificateRequest's issuerRef and if it does not match the api
 	// group name, log a message at a debug level and stop processing.
@@ -76,6 +127,26 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 		return ctrl.Result{}, nil
 	}
 
+	// A CertificateRequest denied by an approver is never signed, mark it as
+	// denied so that cert-manager stops waiting for it.
+	if cmapi.HasCondition(cr, cmapi.CertificateRequestConditionDenied, cmmeta.ConditionTrue) {
+		if cmapi.ReadyReason(cr) == cmapi.CertificateRequestReasonDenied {
+			log.V(4).Info("CertificateRequest has been denied and already marked as denied")
+			return ctrl.Result{}, nil
+		}
+		log.Info("CertificateRequest has been denied, marking it as denied")
+		now := meta.Now()
+		cr.Status.FailureTime = &now
+		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonDenied, "The CertificateRequest was denied by an approval controller")
+	}
+
+	// Wait for the CertificateRequest to be approved, it will be reconciled
+	// again when the Approved condition is set.
+	if !r.DisableApprovalCheck && !cmapi.HasCondition(cr, cmapi.CertificateRequestConditionApproved, cmmeta.ConditionTrue) {
+		log.V(4).Info("CertificateRequest has not been approved yet, ignoring")
+		return ctrl.Result{}, nil
+	}
+
 	if cr.Spec.IsCA {
 		log.Info("AWSPCA certificate does not support online signing of CA certificates")
 		return ctrl.Result{}, nil
@@ -89,7 +160,7 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	}
 	if err := r.Client.Get(ctx, issNamespaceName, &iss); err != nil {
 		log.Error(err, "failed to retrieve AWSPCAIssuer resource", "namespace", req.Namespace, "name", cr.Spec.IssuerRef.Name)
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "Failed to retrieve AWSPCAIssuer resource %s: %v", issNamespaceName, err)
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonIssuerNotFound, "Failed to retrieve AWSPCAIssuer resource %s: %v", issNamespaceName, err)
 		return ctrl.Result{}, err
 	}
 
@@ -97,7 +168,7 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	if !AWSPCAIssuerHasCondition(iss, api.AWSPCAIssuerCondition{Type: api.ConditionReady, Status: api.ConditionTrue}) {
 		err := fmt.Errorf("resource %s is not ready", issNamespaceName)
 		log.Error(err, "failed to retrieve AWSPCAIssuer resource", "namespace", req.Namespace, "name", cr.Spec.IssuerRef.Name)
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "AWSPCAIssuer resource %s is not Ready", issNamespaceName)
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonIssuerNotReady, "AWSPCAIssuer resource %s is not Ready", issNamespaceName)
 		return ctrl.Result{}, err
 	}
 
@@ -106,20 +177,145 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 	if !ok {
 		err := fmt.Errorf("provisioner %s not found", issNamespaceName)
 		log.Error(err, "failed to provisioner for AWSPCAIssuer resource")
-		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonPending, "Failed to load provisioner for AWSPCAIssuer resource %s", issNamespaceName)
+		_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonProvisionerNotFound, "Failed to load provisioner for AWSPCAIssuer resource %s", issNamespaceName)
 		return ctrl.Result{}, err
 	}
 
-	// Sign CertificateRequest
-	signedPEM, _, err := provisioner.Sign(ctx, cr)
+	// Sign CertificateRequest, or retrieve the certificate issued by a
+	// previous reconciliation that ran out of time waiting for it.
+	signCtx, cancel := awsContext(ctx, r.AWSTimeout)
+	var cert *provisioners.Certificate
+	var err error
+	if certificateArn := cr.Annotations[api.CertificateArnAnnotation]; certificateArn != "" {
+		cert, err = provisioner.Retrieve(signCtx, cr.Annotations[api.CertificateAuthorityArnAnnotation], certificateArn)
+	} else {
+		metrics.IssuanceAttempts.WithLabelValues(iss.Namespace, iss.Name).Inc()
+		cert, err = provisioner.Sign(signCtx, cr)
+	}
+	// The deadline of the AWS calls is checked before the context is
+	// canceled.
+	timedOut := signCtx.Err() != nil
+	cancel()
+	if pending, ok := err.(*provisioners.PendingError); ok {
+		log.Info("certificate is still being issued", "arn", pending.CertificateArn)
+		tracing.SetAttributes(ctx, tracing.CertificateArnKey.String(pending.CertificateArn))
+		patch := client.MergeFrom(cr.DeepCopy())
+		if cr.Annotations == nil {
+			cr.Annotations = make(map[string]string)
+		}
+		cr.Annotations[api.CertificateArnAnnotation] = pending.CertificateArn
+		cr.Annotations[api.CertificateAuthorityArnAnnotation] = pending.CAArn
+		if err := r.Client.Patch(ctx, cr, patch); err != nil {
+			log.Error(err, "failed to annotate CertificateRequest with the ARN of the pending certificate", "arn", pending.CertificateArn)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: pendingRequeueDelay}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonCertificatePending,
+			"Waiting for certificate %s to be issued by %s", pending.CertificateArn, pending.CAArn)
+	}
+	r.audit(ctx, log, cr, issNamespaceName, cert, err)
 	if err != nil {
 		log.Error(err, "failed to sign certificate request")
-		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, cmapi.CertificateRequestReasonFailed, "Failed to sign certificate request: %v", err)
+		metrics.IssuanceFailures.WithLabelValues(iss.Namespace, iss.Name, metrics.ErrorCode(err)).Inc()
+		// A temporary failure is retried with backoff, the request stays
+		// pending.
+		if timedOut || ctx.Err() != nil || provisioners.IsRetryableError(err) {
+			_ = r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonSigningRetrying, "Failed to sign certificate request, retrying: %v", err)
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionFalse, api.ReasonSigningFailed, "Failed to sign certificate request: %v", err)
 	}
-	cr.Status.Certificate = signedPEM
-	//cr.Status.CA = trustedCAs
+	tracing.SetAttributes(ctx, tracing.CertificateArnKey.String(cert.Arn))
+	metrics.IssuanceSuccesses.WithLabelValues(iss.Namespace, iss.Name).Inc()
+	metrics.PendingDuration.WithLabelValues(iss.Namespace, iss.Name).Observe(time.Since(cr.CreationTimestamp.Time).Seconds())
 
-	return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionTrue, cmapi.CertificateRequestReasonIssued, "Certificate issued")
+	// Record the private CA that issued the certificate, it may not be the
+	// first CA of the issuer after a failover.
+	patch := client.MergeFrom(cr.DeepCopy())
+	if cr.Annotations == nil {
+		cr.Annotations = make(map[string]string)
+	}
+	cr.Annotations[api.CertificateAuthorityArnAnnotation] = cert.CAArn
+	if err := r.Client.Patch(ctx, cr, patch); err != nil {
+		log.Error(err, "failed to annotate CertificateRequest with the AWS Private CA ARN", "arn", cert.CAArn)
+	}
+
+	if err := r.createIssuedCertificate(ctx, cr, iss.Name, cert); err != nil {
+		log.Error(err, "failed to create AWSPCAIssuedCertificate", "serial", cert.SerialNumber)
+	}
+
+	cr.Status.Certificate = cert.PEM
+	// The bundle of all the CAs of the issuer, so that certificates issued
+	// by any of them are trusted while a CA is rotated.
+	cr.Status.CA = iss.Status.CABundle
+
+	return ctrl.Result{}, r.setStatus(ctx, cr, cmmeta.ConditionTrue, api.ReasonIssued, "Certificate issued by %s", cert.CAArn)
+}
+
+// createIssuedCertificate records the issued certificate in an
+// AWSPCAIssuedCertificate named after its serial number. It is not owned by
+// the CertificateRequest, so that it is kept when the request is deleted.
+// The private CA and serial number used to revoke the certificate are
+// recorded in its status, which only the controller can write.
+func (r *CertificateRequestReconciler) createIssuedCertificate(ctx context.Context, cr *cmapi.CertificateRequest, issuerName string, cert *provisioners.Certificate) error {
+	if cert.SerialNumber == "" {
+		return fmt.Errorf("the issued certificate could not be parsed")
+	}
+
+	certificateName := cr.Annotations[certificateNameAnnotation]
+	labels := map[string]string{api.IssuerLabel: issuerName}
+	if certificateName != "" {
+		labels[api.CertificateNameLabel] = certificateName
+	}
+	ic := &api.AWSPCAIssuedCertificate{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: cr.Namespace,
+			Name:      cert.SerialNumber,
+			Labels:    labels,
+		},
+		Spec: api.AWSPCAIssuedCertificateSpec{
+			IssuerName:             issuerName,
+			CertificateName:        certificateName,
+			CertificateRequestName: cr.Name,
+			CertificateArn:         cert.Arn,
+			CAArn:                  cert.CAArn,
+			SerialNumber:           cert.SerialNumber,
+			SANs:                   cert.SANs,
+			NotBefore:              meta.NewTime(cert.NotBefore),
+			NotAfter:               meta.NewTime(cert.NotAfter),
+		},
+	}
+	if err := r.Client.Create(ctx, ic); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: ic.Namespace, Name: ic.Name}, ic); err != nil {
+			return err
+		}
+	}
+
+	// An existing AWSPCAIssuedCertificate describing another certificate is
+	// left untouched.
+	if ic.Status.CAArn != "" || ic.Spec.CAArn != cert.CAArn || ic.Spec.SerialNumber != cert.SerialNumber {
+		return nil
+	}
+	// A patch, as the AWSPCAIssuedCertificate controller may already have
+	// set its state.
+	patch := client.MergeFrom(ic.DeepCopy())
+	ic.Status.CAArn = cert.CAArn
+	ic.Status.SerialNumber = cert.SerialNumber
+	return r.Client.Status().Patch(ctx, ic, patch)
+}
+
+// audit writes the audit record of a sign attempt. The certificate has been
+// issued or the attempt failed by then, so a record that can not be written
+// is only logged.
+func (r *CertificateRequestReconciler) audit(ctx context.Context, log logr.Logger, cr *cmapi.CertificateRequest, issuer types.NamespacedName, cert *provisioners.Certificate, err error) {
+	if r.Audit == nil {
+		return
+	}
+	if err := r.Audit.Write(ctx, audit.NewRecord(time.Now(), cr, issuer, cert, err)); err != nil {
+		log.Error(err, "failed to write audit record")
+	}
 }
 
 // SetupWithManager initializes the CertificateRequest controller into the
@@ -127,6 +323,8 @@ func (r *CertificateRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result,
 func (r *CertificateRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cmapi.CertificateRequest{}).
+		WithEventFilter(r.Namespaces.predicate()).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
@@ -145,16 +343,35 @@ func AWSPCAIssuerHasCondition(iss api.AWSPCAIssuer, c api.AWSPCAIssuerCondition)
 	return false
 }
 
-func (r *CertificateRequestReconciler) setStatus(ctx context.Context, cr *cmapi.CertificateRequest, status cmmeta.ConditionStatus, reason, message string, args ...interface{}) error {
+// setStatus sets the Ready condition of the CertificateRequest, with the
+// reason of cert-manager matching the given reason, and fires an Event with
+// the given reason.
+func (r *CertificateRequestReconciler) setStatus(ctx context.Context, cr *cmapi.CertificateRequest, status cmmeta.ConditionStatus, reason api.ConditionReason, message string, args ...interface{}) error {
 	completeMessage := fmt.Sprintf(message, args...)
-	apiutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionReady, status, reason, completeMessage)
+	cmapi.SetCondition(cr, cmapi.CertificateRequestConditionReady, status, readyReason(status, reason), completeMessage)
 
 	// Fire an Event to additionally inform users of the change
 	eventType := core.EventTypeNormal
 	if status == cmmeta.ConditionFalse {
 		eventType = core.EventTypeWarning
 	}
-	r.Recorder.Event(cr, eventType, reason, completeMessage)
+	r.Recorder.Event(cr, eventType, string(reason), completeMessage)
 
 	return r.Client.Status().Update(ctx, cr)
 }
+
+// readyReason returns the reason of the Ready condition of a
+// CertificateRequest for the given reason. cert-manager only understands
+// Issued, Failed and Denied, which stop the issuance, and Pending.
+func readyReason(status cmmeta.ConditionStatus, reason api.ConditionReason) string {
+	switch {
+	case status == cmmeta.ConditionTrue:
+		return cmapi.CertificateRequestReasonIssued
+	case reason == api.ReasonDenied:
+		return cmapi.CertificateRequestReasonDenied
+	case reason == api.ReasonSigningFailed:
+		return cmapi.CertificateRequestReasonFailed
+	default:
+		return cmapi.CertificateRequestReasonPending
+	}
+}
@@ -0,0 +1,221 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	cmapi "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/mockacmpca"
+	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newCertificateRequest returns a request for a certificate of the given
+// DNS name, signed by the issuer with the given name.
+func newCertificateRequest(name, issuerName, dnsName string) *cmapi.CertificateRequest {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{dnsName}}, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return &cmapi.CertificateRequest{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:   testNamespace,
+			Name:        name,
+			Annotations: map[string]string{certificateNameAnnotation: name},
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+			Duration: &meta.Duration{Duration: 30 * 24 * time.Hour},
+			IssuerRef: cmmeta.ObjectReference{
+				Name:  issuerName,
+				Kind:  "AWSPCAIssuer",
+				Group: api.GroupVersion.Group,
+			},
+		},
+	}
+}
+
+// createCertificateRequest creates the CertificateRequest and approves it,
+// as the approver of cert-manager would.
+func createCertificateRequest(cr *cmapi.CertificateRequest) {
+	Expect(k8sClient.Create(context.Background(), cr)).To(Succeed())
+	setCertificateRequestCondition(cr.Name, cmapi.CertificateRequestConditionApproved, "cert-manager.io")
+}
+
+// setCertificateRequestCondition sets a True condition of the given type on
+// the CertificateRequest with the given name.
+func setCertificateRequestCondition(name string, conditionType cmapi.CertificateRequestConditionType, reason string) {
+	Eventually(func() error {
+		cr := getCertificateRequest(name)
+		if cr == nil {
+			return fmt.Errorf("CertificateRequest %s not found", name)
+		}
+		cmapi.SetCondition(cr, conditionType, cmmeta.ConditionTrue, reason, "Set by the test")
+		return k8sClient.Status().Update(context.Background(), cr)
+	}).Should(Succeed())
+}
+
+// getCertificateRequest returns the CertificateRequest with the given name,
+// or nil if it can not be retrieved.
+func getCertificateRequest(name string) *cmapi.CertificateRequest {
+	cr := new(cmapi.CertificateRequest)
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, cr); err != nil {
+		return nil
+	}
+	return cr
+}
+
+// certificateRequestReady returns a function returning the status and
+// reason of the Ready condition of the CertificateRequest with the given
+// name, as "<status>/<reason>".
+func certificateRequestReady(name string) func() string {
+	return func() string {
+		cr := getCertificateRequest(name)
+		if cr == nil {
+			return ""
+		}
+		for _, c := range cr.Status.Conditions {
+			if c.Type == cmapi.CertificateRequestConditionReady {
+				return string(c.Status) + "/" + c.Reason
+			}
+		}
+		return ""
+	}
+}
+
+var _ = Describe("CertificateRequestReconciler", func() {
+	ctx := context.Background()
+
+	AfterEach(func() {
+		pca.SetConfig(mockacmpca.Config{})
+	})
+
+	It("signs a CertificateRequest", func() {
+		iss := createReadyIssuer("cr-issuer", testCAArn)
+
+		createCertificateRequest(newCertificateRequest("cr-signed", "cr-issuer", "www.example.com"))
+		Eventually(certificateRequestReady("cr-signed")).Should(Equal("True/Issued"))
+
+		cr := getCertificateRequest("cr-signed")
+		block, _ := pem.Decode(cr.Status.Certificate)
+		Expect(block).ToNot(BeNil())
+		cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert.DNSNames).To(Equal([]string{"www.example.com"}))
+		Expect(cr.Status.CA).To(Equal(iss.Status.CABundle))
+		Expect(cr.Annotations).To(HaveKeyWithValue(api.CertificateAuthorityArnAnnotation, testCAArn))
+
+		ic := new(api.AWSPCAIssuedCertificate)
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: cert.SerialNumber.Text(16)}, ic)).To(Succeed())
+		Expect(ic.Spec.CertificateRequestName).To(Equal("cr-signed"))
+		Expect(ic.Labels).To(HaveKeyWithValue(api.IssuerLabel, "cr-issuer"))
+		Expect(ic.Labels).To(HaveKeyWithValue(api.CertificateNameLabel, "cr-signed"))
+	})
+
+	It("fails a CertificateRequest rejected by AWS", func() {
+		createReadyIssuer("cr-issuer-rejected", testCAArn)
+
+		// The certificate cannot be valid beyond the private CA.
+		cr := newCertificateRequest("cr-failed", "cr-issuer-rejected", "www.example.com")
+		cr.Spec.Duration = &meta.Duration{Duration: 20 * 365 * 24 * time.Hour}
+		createCertificateRequest(cr)
+		Eventually(certificateRequestReady("cr-failed")).Should(Equal("False/Failed"))
+
+		cr = getCertificateRequest("cr-failed")
+		Expect(cr.Status.Certificate).To(BeEmpty())
+		Expect(cr.Status.Conditions[0].Message).To(ContainSubstring("ValidationException"))
+	})
+
+	It("retries a CertificateRequest throttled by AWS", func() {
+		createReadyIssuer("cr-issuer-throttled", testCAArn)
+
+		pca.SetConfig(mockacmpca.Config{ThrottleRate: 1})
+		createCertificateRequest(newCertificateRequest("cr-throttled", "cr-issuer-throttled", "www.example.com"))
+		Eventually(certificateRequestReady("cr-throttled")).Should(Equal("False/Pending"))
+		Expect(getCertificateRequest("cr-throttled").Status.FailureTime).To(BeNil())
+
+		pca.SetConfig(mockacmpca.Config{})
+		Eventually(certificateRequestReady("cr-throttled")).Should(Equal("True/Issued"))
+	})
+
+	It("waits for its issuer to be ready", func() {
+		createCertificateRequest(newCertificateRequest("cr-pending", "cr-issuer-later", "www.example.com"))
+		Eventually(certificateRequestReady("cr-pending")).Should(Equal("False/Pending"))
+
+		createReadyIssuer("cr-issuer-later", testCAArn)
+		Eventually(certificateRequestReady("cr-pending")).Should(Equal("True/Issued"))
+	})
+
+	It("ignores CertificateRequests of other issuer groups", func() {
+		cr := newCertificateRequest("cr-other-group", "cr-issuer", "www.example.com")
+		cr.Spec.IssuerRef.Group = "cert-manager.io"
+		createCertificateRequest(cr)
+		Consistently(certificateRequestReady("cr-other-group"), 3*time.Second).Should(BeEmpty())
+	})
+
+	It("waits for a CertificateRequest to be approved", func() {
+		createReadyIssuer("cr-issuer-approval", testCAArn)
+
+		Expect(k8sClient.Create(ctx, newCertificateRequest("cr-unapproved", "cr-issuer-approval", "www.example.com"))).To(Succeed())
+		Consistently(certificateRequestReady("cr-unapproved"), 3*time.Second).Should(BeEmpty())
+		Expect(getCertificateRequest("cr-unapproved").Annotations).ToNot(HaveKey(api.CertificateArnAnnotation))
+
+		setCertificateRequestCondition("cr-unapproved", cmapi.CertificateRequestConditionApproved, "cert-manager.io")
+		Eventually(certificateRequestReady("cr-unapproved")).Should(Equal("True/Issued"))
+	})
+
+	It("does not sign a denied CertificateRequest", func() {
+		createReadyIssuer("cr-issuer-denied", testCAArn)
+
+		Expect(k8sClient.Create(ctx, newCertificateRequest("cr-denied", "cr-issuer-denied", "www.example.com"))).To(Succeed())
+		setCertificateRequestCondition("cr-denied", cmapi.CertificateRequestConditionDenied, "policy.example.com")
+		Eventually(certificateRequestReady("cr-denied")).Should(Equal("False/Denied"))
+
+		cr := getCertificateRequest("cr-denied")
+		Expect(cr.Status.Certificate).To(BeEmpty())
+		Expect(cr.Status.FailureTime).ToNot(BeNil())
+		Expect(cr.Annotations).ToNot(HaveKey(api.CertificateArnAnnotation))
+	})
+
+	It("keeps the Ready reasons of cert-manager", func() {
+		Expect(readyReason(cmmeta.ConditionTrue, api.ReasonIssued)).To(Equal(cmapi.CertificateRequestReasonIssued))
+		Expect(readyReason(cmmeta.ConditionFalse, api.ReasonSigningFailed)).To(Equal(cmapi.CertificateRequestReasonFailed))
+		Expect(readyReason(cmmeta.ConditionFalse, api.ReasonSigningRetrying)).To(Equal(cmapi.CertificateRequestReasonPending))
+		Expect(readyReason(cmmeta.ConditionFalse, api.ReasonDenied)).To(Equal(cmapi.CertificateRequestReasonDenied))
+		Expect(readyReason(cmmeta.ConditionFalse, api.ReasonIssuerNotReady)).To(Equal(cmapi.CertificateRequestReasonPending))
+	})
+
+	It("does not sign CA certificates", func() {
+		cr := newCertificateRequest("cr-ca", "cr-issuer", "ca.example.com")
+		cr.Spec.IsCA = true
+		createCertificateRequest(cr)
+		Consistently(certificateRequestReady("cr-ca"), 3*time.Second).Should(BeEmpty())
+	})
+})
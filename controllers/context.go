@@ -0,0 +1,42 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+)
+
+// reconcileContext returns the context of a reconciliation, the given
+// parent context or the background context if nil. The parent context of
+// the reconcilers is canceled when the manager stops.
+func reconcileContext(parent context.Context) context.Context {
+	if parent == nil {
+		return context.Background()
+	}
+	return parent
+}
+
+// awsContext returns the context of the AWS Private CA calls of a
+// reconciliation, with the given timeout if not zero. The Kubernetes API
+// calls keep using the context of the reconciliation, so that the failure
+// of AWS calls that timed out can still be recorded.
+func awsContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
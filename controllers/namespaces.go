@@ -0,0 +1,55 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// WatchedNamespaces are the namespaces watched by the controllers, all the
+// namespaces if empty. The cache of the manager must be restricted to the
+// same namespaces.
+type WatchedNamespaces []string
+
+// All returns true if all the namespaces are watched.
+func (w WatchedNamespaces) All() bool {
+	return len(w) == 0
+}
+
+// Contains returns true if the given namespace is watched.
+func (w WatchedNamespaces) Contains(namespace string) bool {
+	if w.All() {
+		return true
+	}
+	for _, ns := range w {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// predicate filters out the events of the objects outside the watched
+// namespaces, e.g. of cluster scoped objects.
+func (w WatchedNamespaces) predicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return w.Contains(e.Meta.GetNamespace()) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return w.Contains(e.Meta.GetNamespace()) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return w.Contains(e.MetaNew.GetNamespace()) },
+		GenericFunc: func(e event.GenericEvent) bool { return w.Contains(e.Meta.GetNamespace()) },
+	}
+}
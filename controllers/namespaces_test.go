@@ -0,0 +1,48 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("WatchedNamespaces", func() {
+	It("watches all the namespaces if empty", func() {
+		var w WatchedNamespaces
+		Expect(w.All()).To(BeTrue())
+		Expect(w.Contains("team-a")).To(BeTrue())
+		Expect(w.Contains("")).To(BeTrue())
+	})
+
+	It("filters out the events of other namespaces", func() {
+		w := WatchedNamespaces{"team-a", "team-b"}
+		Expect(w.All()).To(BeFalse())
+
+		create := func(namespace string) bool {
+			cm := &core.ConfigMap{ObjectMeta: meta.ObjectMeta{Namespace: namespace, Name: "cm"}}
+			return w.predicate().Create(event.CreateEvent{Meta: cm, Object: cm})
+		}
+		Expect(create("team-b")).To(BeTrue())
+		Expect(create("team-c")).To(BeFalse())
+		// Cluster scoped objects are not in a watched namespace.
+		Expect(create("")).To(BeFalse())
+	})
+})
@@ -16,16 +16,24 @@ limitations under the License.
 package controllers
 
 import (
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	certmanagerv1alpha2 "github.com/awspca-issuer/api/v1alpha2"
+	certmanagerv1beta1 "github.com/awspca-issuer/api/v1beta1"
+	cmapi "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/mockacmpca"
+	"github.com/awspca-issuer/provisioners"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -33,6 +41,13 @@ import (
 	// +kubebuilder:scaffold:imports
 )
 
+// The private CAs of the mock AWS Private CA backend of the suite.
+const (
+	testRegion = "us-east-1"
+	testCAArn  = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000001"
+	otherCAArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000002"
+)
+
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
@@ -40,6 +55,14 @@ var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
 
+// pca is the mock AWS Private CA backend of the controllers, served by
+// pcaServer.
+var pca *mockacmpca.Server
+var pcaServer *httptest.Server
+
+// stopManager stops the manager running the controllers.
+var stopManager chan struct{}
+
 func TestAPIs(t *testing.T) {
 	if os.Getenv("CI") == "true" {
 		t.SkipNow()
@@ -57,7 +80,11 @@ var _ = BeforeSuite(func(done Done) {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths: []string{filepath.Join("..", "config", "crd", "bases")},
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "config", "crd", "bases"),
+			// The v1 CertificateRequest CRD of cert-manager.
+			filepath.Join("testdata", "crds"),
+		},
 	}
 
 	var err error
@@ -68,17 +95,72 @@ var _ = BeforeSuite(func(done Done) {
 	err = certmanagerv1alpha2.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = certmanagerv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = cmapi.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).ToNot(HaveOccurred())
 	Expect(k8sClient).ToNot(BeNil())
 
+	By("starting the mock AWS Private CA")
+	pca, err = mockacmpca.NewServer([]string{testCAArn, otherCAArn}, mockacmpca.Config{})
+	Expect(err).ToNot(HaveOccurred())
+	pcaServer = httptest.NewServer(pca)
+	provisioners.SetEndpoint(pcaServer.URL)
+
+	By("starting the controllers")
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme.Scheme,
+		MetricsBindAddress: "0",
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&AWSPCAIssuerReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AWSPCAIssuer"),
+		Clock:    clock.RealClock{},
+		Recorder: mgr.GetEventRecorderFor("awspcaissuer-controller"),
+
+		CAExpiryWarningThreshold: 30 * 24 * time.Hour,
+		// The default interval, the issuers must be reconciled on the
+		// changes of their secrets. The specs changing the mock backend
+		// trigger the checks with checkIssuer.
+		CAExpiryCheckInterval: time.Hour,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&CertificateRequestReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("CertificateRequest"),
+		Recorder: mgr.GetEventRecorderFor("certificaterequests-controller"),
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	stopManager = make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(stopManager)).To(Succeed())
+	}()
+
+	SetDefaultEventuallyTimeout(30 * time.Second)
+	SetDefaultEventuallyPollingInterval(100 * time.Millisecond)
+
 	close(done)
 }, 60)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	if stopManager != nil {
+		close(stopManager)
+	}
+	if pcaServer != nil {
+		pcaServer.Close()
+	}
 	err := testEnv.Stop()
 	Expect(err).ToNot(HaveOccurred())
 })
@@ -0,0 +1,254 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// trustBundleLabel marks the ConfigMaps holding the CA bundle of an
+	// issuer.
+	trustBundleLabel = "certmanager.awspca/trust-bundle"
+	// trustBundleIssuerAnnotation holds the namespace/name of the issuer
+	// that wrote a trust bundle ConfigMap. Issuer names may be too long for
+	// a label value.
+	trustBundleIssuerAnnotation = "certmanager.awspca/issuer"
+	// trustBundleNamespaceLabel must be set to "true" on a namespace for the
+	// issuers of other namespaces to write their trust bundle to it.
+	trustBundleNamespaceLabel = "certmanager.awspca/trust-bundles"
+)
+
+// syncTrustBundle writes the CA bundle of the issuer to the ConfigMaps of
+// its trust bundle, and deletes the ConfigMaps in the namespaces that are no
+// longer selected. Nothing is written until the bundle is known.
+func (r *AWSPCAIssuerReconciler) syncTrustBundle(ctx context.Context, iss *api.AWSPCAIssuer) error {
+	tb := iss.Spec.TrustBundle
+	if len(iss.Status.CABundle) == 0 {
+		return nil
+	}
+
+	namespaces, rejected, err := r.trustBundleNamespaces(ctx, iss)
+	if err != nil {
+		return err
+	}
+
+	issuerKey := types.NamespacedName{Namespace: iss.Namespace, Name: iss.Name}.String()
+	for ns := range namespaces {
+		cm := &core.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: tb.ConfigMapName},
+		}
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+			// Never overwrite a ConfigMap that was not created by the issuer.
+			if cm.ResourceVersion != "" && cm.Annotations[trustBundleIssuerAnnotation] != issuerKey {
+				return fmt.Errorf("ConfigMap %s/%s is not managed by AWSPCAIssuer %s", ns, cm.Name, issuerKey)
+			}
+			if cm.Labels == nil {
+				cm.Labels = make(map[string]string)
+			}
+			cm.Labels[trustBundleLabel] = "true"
+			if cm.Annotations == nil {
+				cm.Annotations = make(map[string]string)
+			}
+			cm.Annotations[trustBundleIssuerAnnotation] = issuerKey
+			cm.Data = map[string]string{tb.Key: string(iss.Status.CABundle)}
+			return nil
+		})
+		switch {
+		case apierrors.IsNotFound(err):
+			// An explicitly listed namespace may not exist yet.
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to write trust bundle ConfigMap %s/%s: %v", ns, tb.ConfigMapName, err)
+		}
+	}
+
+	if err := r.deleteTrustBundles(ctx, iss, func(cm *core.ConfigMap) bool {
+		return namespaces[cm.Namespace] && cm.Name == tb.ConfigMapName
+	}); err != nil {
+		return err
+	}
+
+	if len(rejected) > 0 {
+		return fmt.Errorf("spec.trustBundle.namespaces: namespaces %s do not accept trust bundles of other namespaces, they must be labeled %s=true",
+			strings.Join(rejected, ", "), trustBundleNamespaceLabel)
+	}
+	return nil
+}
+
+// trustBundleNamespaces returns the namespaces selected by the trust bundle
+// of the issuer that accept it, and the listed namespaces that do not. A
+// namespace other than the one of the issuer accepts trust bundles if it has
+// the trustBundleNamespaceLabel, so that an issuer cannot write ConfigMaps to
+// the namespaces of other tenants. The selected namespaces without the label
+// are ignored.
+func (r *AWSPCAIssuerReconciler) trustBundleNamespaces(ctx context.Context, iss *api.AWSPCAIssuer) (map[string]bool, []string, error) {
+	tb := iss.Spec.TrustBundle
+	namespaces := make(map[string]bool)
+	if len(tb.Namespaces) == 0 && tb.NamespaceSelector == nil {
+		namespaces[iss.Namespace] = true
+		return namespaces, nil, nil
+	}
+
+	var rejected []string
+	for _, name := range tb.Namespaces {
+		if name == iss.Namespace {
+			namespaces[name] = true
+			continue
+		}
+		// Namespaces are cluster scoped, they cannot be read from a cache
+		// of some namespaces.
+		if !r.Namespaces.All() {
+			return nil, nil, fmt.Errorf("spec.trustBundle.namespaces: namespace %s is not supported when the controller only watches some namespaces, only the namespace of the issuer is", name)
+		}
+		ns := new(core.Namespace)
+		err := r.Client.Get(ctx, types.NamespacedName{Name: name}, ns)
+		switch {
+		case apierrors.IsNotFound(err):
+			// An explicitly listed namespace may not exist yet.
+		case err != nil:
+			return nil, nil, fmt.Errorf("failed to retrieve namespace %s: %v", name, err)
+		case acceptsTrustBundles(ns):
+			namespaces[name] = true
+		default:
+			rejected = append(rejected, name)
+		}
+	}
+
+	if tb.NamespaceSelector != nil {
+		if !r.Namespaces.All() {
+			return nil, nil, fmt.Errorf("spec.trustBundle.namespaceSelector is not supported when the controller only watches some namespaces")
+		}
+		selector, err := meta.LabelSelectorAsSelector(tb.NamespaceSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid spec.trustBundle.namespaceSelector: %v", err)
+		}
+		var list core.NamespaceList
+		if err := r.Client.List(ctx, &list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, nil, fmt.Errorf("failed to list namespaces: %v", err)
+		}
+		for i := range list.Items {
+			ns := &list.Items[i]
+			if ns.Name == iss.Namespace || acceptsTrustBundles(ns) {
+				namespaces[ns.Name] = true
+			}
+		}
+	}
+	return namespaces, rejected, nil
+}
+
+// acceptsTrustBundles returns true if the issuers of other namespaces may
+// write their trust bundle to the namespace.
+func acceptsTrustBundles(ns *core.Namespace) bool {
+	return ns.Status.Phase != core.NamespaceTerminating && ns.Labels[trustBundleNamespaceLabel] == "true"
+}
+
+// deleteTrustBundles deletes the trust bundle ConfigMaps written by the
+// issuer, except the ones to keep.
+func (r *AWSPCAIssuerReconciler) deleteTrustBundles(ctx context.Context, iss *api.AWSPCAIssuer, keep func(*core.ConfigMap) bool) error {
+	var list core.ConfigMapList
+	if err := r.Client.List(ctx, &list, client.MatchingLabels{trustBundleLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list trust bundle ConfigMaps: %v", err)
+	}
+
+	issuerKey := types.NamespacedName{Namespace: iss.Namespace, Name: iss.Name}.String()
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if cm.Annotations[trustBundleIssuerAnnotation] != issuerKey || (keep != nil && keep(cm)) {
+			continue
+		}
+		if err := r.Client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete trust bundle ConfigMap %s/%s: %v", cm.Namespace, cm.Name, err)
+		}
+	}
+	return nil
+}
+
+// mapTrustBundleNamespace enqueues the issuers distributing their CA bundle
+// when a namespace changes, as it may now be selected or no longer be.
+func (r *AWSPCAIssuerReconciler) mapTrustBundleNamespace(o handler.MapObject) []reconcile.Request {
+	var list api.AWSPCAIssuerList
+	if err := r.Client.List(context.Background(), &list); err != nil {
+		r.Log.Error(err, "failed to list AWSPCAIssuer resources")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, iss := range list.Items {
+		if iss.Spec.TrustBundle != nil {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: iss.Namespace, Name: iss.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// isTrustBundle returns true if the object is a trust bundle ConfigMap.
+func isTrustBundle(o meta.Object) bool {
+	return o.GetLabels()[trustBundleLabel] == "true"
+}
+
+// trustBundlePredicate filters out the events of the ConfigMaps that are not
+// trust bundles. The updates removing the label are kept, so that the issuer
+// restores it.
+func trustBundlePredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isTrustBundle(e.Meta) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isTrustBundle(e.Meta) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return isTrustBundle(e.MetaOld) || isTrustBundle(e.MetaNew) },
+		GenericFunc: func(e event.GenericEvent) bool { return isTrustBundle(e.Meta) },
+	}
+}
+
+// mapTrustBundleConfigMap enqueues the issuer that wrote a trust bundle
+// ConfigMap when it changes, to restore its content.
+func mapTrustBundleConfigMap(o handler.MapObject) []reconcile.Request {
+	if !isTrustBundle(o.Meta) {
+		return nil
+	}
+	parts := strings.SplitN(o.Meta.GetAnnotations()[trustBundleIssuerAnnotation], "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: parts[0], Name: parts[1]},
+	}}
+}
+
+// hasFinalizer returns true if the given finalizer is set on the object.
+func hasFinalizer(o meta.Object, finalizer string) bool {
+	for _, f := range o.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
@@ -5,13 +5,25 @@ go 1.13
 require (
 	github.com/aws/aws-sdk-go v1.24.1
 	github.com/go-logr/logr v0.1.0
+	github.com/google/gofuzz v1.0.0
 	github.com/jetstack/cert-manager v0.13.1
 	github.com/onsi/ginkgo v1.11.0
 	github.com/onsi/gomega v1.8.1
+	github.com/prometheus/client_golang v1.0.0
+	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90
+	go.opentelemetry.io/otel v1.0.0-RC1
+	go.opentelemetry.io/otel/sdk v1.0.0-RC1
+	go.opentelemetry.io/otel/trace v1.0.0-RC1
+	go.uber.org/zap v1.10.0
 	k8s.io/api v0.17.0
 	k8s.io/apimachinery v0.17.0
 	k8s.io/client-go v0.17.0
 	k8s.io/utils v0.0.0-20191114184206-e782cd3c129f
 	sigs.k8s.io/controller-runtime v0.4.0
 	sigs.k8s.io/controller-tools v0.2.5 // indirect
+	sigs.k8s.io/yaml v1.1.0
 )
+
+// OpenTelemetry only requires go-cmp v0.5 in its tests, keep the version the
+// Kubernetes dependencies are built with.
+replace github.com/google/go-cmp v0.5.6 => github.com/google/go-cmp v0.3.0
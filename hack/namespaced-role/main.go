@@ -0,0 +1,136 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// namespaced-role generates the Role granting the controller its permissions
+// in a watched namespace from the ClusterRole generated by controller-gen.
+// The rules of the cluster scoped resources, the Namespaces and the CRDs with
+// the Cluster scope, are removed, as a Role cannot grant them.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScopedCoreResources are the cluster scoped resources of the core
+// API group the controller may be granted.
+var clusterScopedCoreResources = []string{"namespaces", "nodes", "persistentvolumes", "componentstatuses"}
+
+// crd is the part of a CustomResourceDefinition read to find its scope.
+type crd struct {
+	Spec struct {
+		Group string `json:"group"`
+		Names struct {
+			Plural string `json:"plural"`
+		} `json:"names"`
+		Scope string `json:"scope"`
+	} `json:"spec"`
+}
+
+func main() {
+	var rolePath, crdDir, name string
+	flag.StringVar(&rolePath, "role", "config/rbac/role.yaml", "The ClusterRole generated by controller-gen.")
+	flag.StringVar(&crdDir, "crds", "config/crd/bases", "The directory of the CRDs of the controller.")
+	flag.StringVar(&name, "name", "awspca-issuer-manager-role", "The name of the Role.")
+	flag.Parse()
+
+	clusterScoped := make(map[string]bool)
+	for _, resource := range clusterScopedCoreResources {
+		clusterScoped["/"+resource] = true
+	}
+	paths, err := filepath.Glob(filepath.Join(crdDir, "*.yaml"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, path := range paths {
+		var c crd
+		if err := readYAML(path, &c); err != nil {
+			log.Fatal(err)
+		}
+		if c.Spec.Scope == "Cluster" {
+			clusterScoped[c.Spec.Group+"/"+c.Spec.Names.Plural] = true
+		}
+	}
+
+	var clusterRole rbacv1.ClusterRole
+	if err := readYAML(rolePath, &clusterRole); err != nil {
+		log.Fatal(err)
+	}
+	role := rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	for _, rule := range clusterRole.Rules {
+		if rule = namespacedRule(rule, clusterScoped); len(rule.Resources) > 0 {
+			role.Rules = append(role.Rules, rule)
+		}
+	}
+
+	data, err := yaml.Marshal(role)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("# Generated from %s by 'make manifests', without the rules of the\n", rolePath)
+	fmt.Println("# cluster scoped resources. Apply it with role_binding.yaml in each")
+	fmt.Println("# namespace watched with --namespaces.")
+	fmt.Println()
+	fmt.Println("---")
+	os.Stdout.Write(data)
+}
+
+// namespacedRule returns the rule without its cluster scoped resources, and
+// their subresources.
+func namespacedRule(rule rbacv1.PolicyRule, clusterScoped map[string]bool) rbacv1.PolicyRule {
+	var resources []string
+	for _, resource := range rule.Resources {
+		base := resource
+		if i := strings.IndexByte(resource, '/'); i >= 0 {
+			base = resource[:i]
+		}
+		cluster := false
+		for _, group := range rule.APIGroups {
+			cluster = cluster || clusterScoped[group+"/"+base]
+		}
+		if !cluster {
+			resources = append(resources, resource)
+		}
+	}
+	rule.Resources = resources
+	return rule
+}
+
+// readYAML decodes the YAML file at the given path, skipping the document
+// separator written by controller-gen.
+func readYAML(path string, obj interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("---"))
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health contains the readiness checks and the AWS Private CA status
+// check of the AWSPCA issuer, and the debug server listing the loaded
+// provisioners and serving that check.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/awspca-issuer/provisioners"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// ProvisionersPath is the path of the debug endpoint listing the loaded
+// provisioners.
+const ProvisionersPath = "/debug/provisioners"
+
+// AWSPath is the path of the debug endpoint serving the AWSReachable check.
+const AWSPath = "/debug/aws"
+
+// AWSReachable is a check failing when every loaded provisioner has failed
+// its last verification with AWS Private CA, e.g. because the AWS endpoints
+// cannot be reached. Provisioners not verified yet are not considered
+// failed, and the check succeeds if no provisioner is loaded.
+//
+// It is only a readiness check with --aws-readiness-check, see
+// ReadinessChecks.
+func AWSReachable(_ *http.Request) error {
+	infos := provisioners.List()
+	if len(infos) == 0 {
+		return nil
+	}
+	var failures []string
+	for _, info := range infos {
+		v := info.Verification
+		if v == nil || v.Error == "" {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s/%s: %s", info.Namespace, info.Name, v.Error))
+	}
+	return fmt.Errorf("all the issuers failed their last AWS Private CA verification: %s", strings.Join(failures, "; "))
+}
+
+// ReadinessChecks returns the readiness checks of the manager by name. The
+// AWSReachable check is only included if aws is true: the webhooks are
+// served by the same pod and do not need AWS, they are also unavailable
+// while the pod is not ready.
+func ReadinessChecks(aws bool) map[string]healthz.Checker {
+	checks := map[string]healthz.Checker{"ping": healthz.Ping}
+	if aws {
+		checks["aws"] = AWSReachable
+	}
+	return checks
+}
+
+// AWSHandler serves the AWSReachable check, with the 503 status code if it
+// fails.
+func AWSHandler(w http.ResponseWriter, r *http.Request) {
+	if err := AWSReachable(r); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// ProvisionersHandler serves the loaded provisioners as JSON, without their
+// credentials.
+func ProvisionersHandler(w http.ResponseWriter, _ *http.Request) {
+	infos := provisioners.List()
+	if infos == nil {
+		infos = []provisioners.Info{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(infos)
+}
+
+// DebugServer serves the debug endpoints. It runs on every replica of the
+// manager, not only on the leader.
+type DebugServer struct {
+	// Addr is the address the server binds to.
+	Addr string
+	Log  logr.Logger
+}
+
+// Start implements manager.Runnable, it serves the debug endpoints until
+// stop is closed.
+func (s *DebugServer) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ProvisionersPath, ProvisionersHandler)
+	mux.HandleFunc(AWSPath, AWSHandler)
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %v", s.Addr, err)
+	}
+	server := &http.Server{Handler: mux}
+
+	errs := make(chan error, 1)
+	go func() {
+		s.Log.Info("starting debug server", "addr", l.Addr().String())
+		if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+			errs <- err
+		}
+	}()
+
+	select {
+	case <-stop:
+		return server.Shutdown(context.Background())
+	case err := <-errs:
+		return err
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *DebugServer) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,130 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awspca-issuer/provisioners"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+const testCAArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555"
+
+func TestAWSReachable(t *testing.T) {
+	if err := AWSReachable(nil); err != nil {
+		t.Errorf("AWSReachable() without provisioners error = %v", err)
+	}
+
+	cas := []provisioners.CertificateAuthority{{Arn: testCAArn, Region: "us-east-1"}}
+	issuer1 := types.NamespacedName{Namespace: "default", Name: "issuer-1"}
+	issuer2 := types.NamespacedName{Namespace: "default", Name: "issuer-2"}
+	provisioners.Store(issuer1, provisioners.NewProvisioner(true, "access", "secret", cas, provisioners.Options{}))
+	provisioners.Store(issuer2, provisioners.NewProvisioner(true, "access", "secret", cas, provisioners.Options{}))
+	defer provisioners.Delete(issuer1)
+	defer provisioners.Delete(issuer2)
+
+	failure := errors.New("RequestError: send request failed")
+	provisioners.SetVerification(issuer1, time.Now(), failure)
+	if err := AWSReachable(nil); err != nil {
+		t.Errorf("AWSReachable() with an unverified provisioner error = %v", err)
+	}
+	provisioners.SetVerification(issuer2, time.Now(), nil)
+	if err := AWSReachable(nil); err != nil {
+		t.Errorf("AWSReachable() with a verified provisioner error = %v", err)
+	}
+	provisioners.SetVerification(issuer2, time.Now(), failure)
+	if err := AWSReachable(nil); err == nil || !strings.Contains(err.Error(), "default/issuer-2: RequestError") {
+		t.Errorf("AWSReachable() with failed provisioners error = %v", err)
+	}
+
+	provisioners.Delete(issuer2)
+	if err := AWSReachable(nil); err == nil {
+		t.Error("AWSReachable() after a deletion error = nil, want the failure of issuer-1")
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	issuer := types.NamespacedName{Namespace: "default", Name: "issuer"}
+	cas := []provisioners.CertificateAuthority{{Arn: testCAArn, Region: "us-east-1"}}
+	provisioners.Store(issuer, provisioners.NewProvisioner(true, "access", "secret", cas, provisioners.Options{}))
+	defer provisioners.Delete(issuer)
+	provisioners.SetVerification(issuer, time.Now(), errors.New("RequestError: send request failed"))
+
+	for aws, want := range map[bool]int{false: 200, true: 500} {
+		rec := httptest.NewRecorder()
+		handler := &healthz.Handler{Checks: ReadinessChecks(aws)}
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != want {
+			t.Errorf("readyz with ReadinessChecks(%v) and a failed provisioner status = %d, want %d", aws, rec.Code, want)
+		}
+	}
+
+	provisioners.SetVerification(issuer, time.Now(), nil)
+	rec := httptest.NewRecorder()
+	handler := &healthz.Handler{Checks: ReadinessChecks(true)}
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 200 {
+		t.Errorf("readyz with ReadinessChecks(true) and a verified provisioner status = %d, want 200", rec.Code)
+	}
+}
+
+func TestProvisionersHandler(t *testing.T) {
+	issuer := types.NamespacedName{Namespace: "default", Name: "issuer"}
+	cas := []provisioners.CertificateAuthority{{Arn: testCAArn, Region: "us-east-1", Weight: 10}}
+	provisioners.Store(issuer, provisioners.NewProvisioner(true, "AKIDEXAMPLE", "wJalrXUtnFEMI", cas, provisioners.Options{}))
+	defer provisioners.Delete(issuer)
+	provisioners.SetVerification(issuer, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), nil)
+
+	rec := httptest.NewRecorder()
+	ProvisionersHandler(rec, httptest.NewRequest("GET", ProvisionersPath, nil))
+	body := rec.Body.String()
+	for _, want := range []string{`"name": "issuer"`, `"arn": "` + testCAArn + `"`, `"weight": 10`, `"time": "2020-01-02T03:04:05Z"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("ProvisionersHandler() = %s, want %s", body, want)
+		}
+	}
+	for _, secret := range []string{"AKIDEXAMPLE", "wJalrXUtnFEMI"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("ProvisionersHandler() = %s, leaks the credentials", body)
+		}
+	}
+}
+
+func TestAWSHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	AWSHandler(rec, httptest.NewRequest("GET", AWSPath, nil))
+	if rec.Code != 200 {
+		t.Errorf("AWSHandler() without provisioners status = %d, want 200", rec.Code)
+	}
+
+	issuer := types.NamespacedName{Namespace: "default", Name: "issuer"}
+	cas := []provisioners.CertificateAuthority{{Arn: testCAArn, Region: "us-east-1"}}
+	provisioners.Store(issuer, provisioners.NewProvisioner(true, "access", "secret", cas, provisioners.Options{}))
+	defer provisioners.Delete(issuer)
+	provisioners.SetVerification(issuer, time.Now(), errors.New("RequestError: send request failed"))
+
+	rec = httptest.NewRecorder()
+	AWSHandler(rec, httptest.NewRequest("GET", AWSPath, nil))
+	if rec.Code != 503 || !strings.Contains(rec.Body.String(), "default/issuer: RequestError") {
+		t.Errorf("AWSHandler() with a failed provisioner = %d %s, want 503 with the failure", rec.Code, rec.Body.String())
+	}
+}
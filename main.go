@@ -16,17 +16,34 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
-	awspcav1alpha2 "github.com/awspca-issuer/api/v1alpha2"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	configv1alpha1 "github.com/awspca-issuer/api/config/v1alpha1"
+	awspcav1alpha2 "github.com/awspca-issuer/api/v1alpha2"
+	awspcav1beta1 "github.com/awspca-issuer/api/v1beta1"
 
-	certmanager "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
+	"github.com/awspca-issuer/audit"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/health"
+	"github.com/awspca-issuer/provisioners"
+	"github.com/awspca-issuer/tracing"
+	"github.com/go-logr/logr"
 	"github.com/awspca-issuer/controllers"
+	uzap "go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 )
@@ -40,53 +57,308 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = certmanager.AddToScheme(scheme)
 	_ = awspcav1alpha2.AddToScheme(scheme)
+	_ = awspcav1beta1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+	cfg := configv1alpha1.ControllerManagerConfig{
+		MetricsBindAddress:       ":8080",
+		HealthProbeBindAddress:   ":8081",
+		Webhook:                  configv1alpha1.WebhookConfig{Enable: os.Getenv("ENABLE_WEBHOOKS") == "true", Port: 443},
+		AWSTimeout:               metav1.Duration{Duration: 2 * time.Minute},
+		CAExpiryWarningThreshold: metav1.Duration{Duration: 30 * 24 * time.Hour},
+		CAExpiryCheckInterval:    metav1.Duration{Duration: time.Hour},
+		Concurrency: configv1alpha1.ConcurrencyConfig{
+			AWSPCAIssuer:            1,
+			CertificateRequest:      1,
+			AWSPCAIssuedCertificate: 1,
+		},
+		LeaderElection: configv1alpha1.LeaderElectionConfig{ResourceName: "controller-leader-election-helper"},
+		LogLevel:       "info",
+	}
+	var configFile string
+	flag.StringVar(&configFile, "config", "",
+		"Path of a ControllerManagerConfig file. The flags set on the command line override the values of the file.")
+	flag.StringVar(&cfg.MetricsBindAddress, "metrics-addr", cfg.MetricsBindAddress, "The address the metric endpoint binds to.")
+	flag.StringVar(&cfg.HealthProbeBindAddress, "health-probe-addr", cfg.HealthProbeBindAddress,
+		"The address the health probe endpoints bind to. The probes are disabled if empty.")
+	flag.BoolVar(&cfg.AWSReadinessCheck, "aws-readiness-check", cfg.AWSReadinessCheck,
+		"Fail the readiness probe when every issuer failed its last verification with AWS Private CA. "+
+			"The webhooks are served by the same pod and are then unavailable too.")
+	flag.StringVar(&cfg.DebugBindAddress, "debug-addr", cfg.DebugBindAddress,
+		"The address the debug endpoints bind to, e.g. "+health.ProvisionersPath+" and "+health.AWSPath+". They are disabled if empty.")
+	flag.BoolVar(&cfg.LeaderElection.LeaderElect, "enable-leader-election", cfg.LeaderElection.LeaderElect,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&cfg.LeaderElection.ResourceNamespace, "leader-election-namespace", cfg.LeaderElection.ResourceNamespace,
+		"Namespace of the leader election lock. Defaults to the cluster resource namespace.")
+	flag.StringVar(&cfg.LeaderElection.ResourceName, "leader-election-id", cfg.LeaderElection.ResourceName,
+		"Name of the leader election lock.")
+	flag.Var((*stringSlice)(&cfg.Namespaces), "namespaces",
+		"Comma separated list of the namespaces watched by the controllers. All the namespaces are watched if empty.")
+	flag.StringVar(&cfg.ClusterResourceNamespace, "cluster-resource-namespace", cfg.ClusterResourceNamespace,
+		"Namespace of the resources of the controller manager that do not belong to an issuer. "+
+			"Defaults to the namespace the controller manager runs in.")
+	flag.DurationVar(&cfg.CAExpiryWarningThreshold.Duration, "ca-expiry-warning-threshold", cfg.CAExpiryWarningThreshold.Duration,
+		"Remaining validity of a private CA certificate below which issuers report the CAExpiringSoon condition.")
+	flag.DurationVar(&cfg.CAExpiryCheckInterval.Duration, "ca-expiry-check-interval", cfg.CAExpiryCheckInterval.Duration,
+		"How often the expiry of the private CA of each issuer is checked. Set to 0 to disable the periodic check.")
+	flag.BoolVar(&cfg.Webhook.Enable, "enable-webhooks", cfg.Webhook.Enable,
+		"Enable the conversion, defaulting and validating webhooks for AWSPCAIssuer resources. Requires a serving certificate. "+
+			"Defaults to true if the ENABLE_WEBHOOKS environment variable is set to true.")
+	flag.IntVar(&cfg.Webhook.Port, "webhook-port", cfg.Webhook.Port, "The port the webhook server binds to.")
+	flag.StringVar(&cfg.Webhook.CertDir, "webhook-cert-dir", cfg.Webhook.CertDir,
+		"Directory of the serving certificate of the webhook server, tls.crt and tls.key. "+
+			"Defaults to /tmp/k8s-webhook-server/serving-certs.")
+	flag.BoolVar(&cfg.DisableApprovalCheck, "disable-approval-check", cfg.DisableApprovalCheck,
+		"Sign CertificateRequests without waiting for them to be approved. "+
+			"Required with versions of cert-manager older than v1.3, which do not support the approval flow.")
+	flag.BoolVar(&cfg.AllowAmbientCredentials, "allow-ambient-credentials", cfg.AllowAmbientCredentials,
+		"Let the issuers without an AWS credentials secret use the default AWS credential chain of the controller, e.g. its IAM role. "+
+			"Anyone who can create an issuer is then granted the permissions of that role.")
+	flag.StringVar(&cfg.AuditSink, "audit-sink", cfg.AuditSink,
+		"Where to write the audit record of every sign attempt: stdout, file:<path> or resource for AWSPCAAuditRecord resources. "+
+			"The audit trail is disabled if empty.")
+	flag.StringVar(&cfg.TraceExporter, "trace-exporter", cfg.TraceExporter,
+		"Where to export the OpenTelemetry spans of the reconciliations and AWS calls: stdout or otlp:<url> of an OTLP/HTTP collector. "+
+			"Tracing is disabled if empty.")
+	flag.StringVar(&cfg.AWSEndpoint, "aws-endpoint", cfg.AWSEndpoint,
+		"URL of the AWS Private CA API to use instead of the regional endpoints of AWS, e.g. a mock server for tests.")
+	flag.DurationVar(&cfg.AWSTimeout.Duration, "aws-timeout", cfg.AWSTimeout.Duration,
+		"Deadline of the AWS Private CA calls of a reconciliation, including the wait for a certificate to be issued. "+
+			"Set to 0 to disable the deadline.")
+	flag.IntVar(&cfg.Concurrency.AWSPCAIssuer, "awspcaissuer-concurrency", cfg.Concurrency.AWSPCAIssuer,
+		"Maximum number of concurrent reconciliations of AWSPCAIssuer resources.")
+	flag.IntVar(&cfg.Concurrency.CertificateRequest, "certificaterequest-concurrency", cfg.Concurrency.CertificateRequest,
+		"Maximum number of concurrent reconciliations of CertificateRequest resources.")
+	flag.IntVar(&cfg.Concurrency.AWSPCAIssuedCertificate, "awspcaissuedcertificate-concurrency", cfg.Concurrency.AWSPCAIssuedCertificate,
+		"Maximum number of concurrent reconciliations of AWSPCAIssuedCertificate resources.")
+	flag.Var((*float32Value)(&cfg.RateLimits.QPS), "kube-api-qps",
+		"Sustained number of queries per second to the Kubernetes API. The client default is used if 0.")
+	flag.IntVar(&cfg.RateLimits.Burst, "kube-api-burst", cfg.RateLimits.Burst,
+		"Maximum burst of queries to the Kubernetes API. The client default is used if 0.")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Minimum level of the logs, one of error, info or debug.")
 	flag.Parse()
 
-	ctrl.SetLogger(zap.Logger(true))
+	var err error
+	if configFile != "" {
+		err = configv1alpha1.Load(configFile, &cfg)
+		// Parse the command line again, so that the flags override the
+		// values of the file.
+		flag.Parse()
+	}
+	if err == nil {
+		err = cfg.Validate()
+	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
-	})
+	ctrl.SetLogger(newLogger(cfg.LogLevel))
+	if err != nil {
+		setupLog.Error(err, "invalid configuration", "config", configFile)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(cfg.TraceExporter)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		// The pending spans are flushed when the manager stops.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			setupLog.Error(err, "unable to export the pending spans")
+		}
+	}()
+
+	if cfg.AWSEndpoint != "" {
+		setupLog.Info("using AWS Private CA endpoint", "url", cfg.AWSEndpoint)
+		provisioners.SetEndpoint(cfg.AWSEndpoint)
+	}
+
+	// The context of the reconciliations is canceled when the manager stops,
+	// so that the pending AWS calls are interrupted.
+	stop := ctrl.SetupSignalHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	restConfig := ctrl.GetConfigOrDie()
+	if cfg.RateLimits.QPS > 0 {
+		restConfig.QPS = cfg.RateLimits.QPS
+	}
+	if cfg.RateLimits.Burst > 0 {
+		restConfig.Burst = cfg.RateLimits.Burst
+	}
+	options := ctrl.Options{
+		Scheme:                  scheme,
+		MetricsBindAddress:      cfg.MetricsBindAddress,
+		HealthProbeBindAddress:  cfg.HealthProbeBindAddress,
+		LeaderElection:          cfg.LeaderElection.LeaderElect,
+		LeaderElectionNamespace: cfg.LeaderElection.ResourceNamespace,
+		LeaderElectionID:        cfg.LeaderElection.ResourceName,
+		Port:                    cfg.Webhook.Port,
+		CertDir:                 cfg.Webhook.CertDir,
+	}
+	if options.LeaderElectionNamespace == "" {
+		options.LeaderElectionNamespace = cfg.ClusterResourceNamespace
+	}
+	switch len(cfg.Namespaces) {
+	case 0:
+	case 1:
+		setupLog.Info("watching a single namespace", "namespace", cfg.Namespaces[0])
+		options.Namespace = cfg.Namespaces[0]
+	default:
+		setupLog.Info("watching namespaces", "namespaces", cfg.Namespaces)
+		options.NewCache = cache.MultiNamespacedCacheBuilder(cfg.Namespaces)
+	}
+	mgr, err := ctrl.NewManager(restConfig, options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to add liveness check")
+		os.Exit(1)
+	}
+	for name, check := range health.ReadinessChecks(cfg.AWSReadinessCheck) {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to add readiness check", "check", name)
+			os.Exit(1)
+		}
+	}
+	if cfg.DebugBindAddress != "" {
+		if err := mgr.Add(&health.DebugServer{Addr: cfg.DebugBindAddress, Log: ctrl.Log.WithName("debug")}); err != nil {
+			setupLog.Error(err, "unable to add debug server")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.AWSPCAIssuerReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("AWSPCAIssuer"),
 		Clock:    clock.RealClock{},
 		Recorder: mgr.GetEventRecorderFor("awspcaissuer-controller"),
+
+		CAExpiryWarningThreshold: cfg.CAExpiryWarningThreshold.Duration,
+		CAExpiryCheckInterval:    cfg.CAExpiryCheckInterval.Duration,
+
+		Context:                 ctx,
+		AWSTimeout:              cfg.AWSTimeout.Duration,
+		MaxConcurrentReconciles: cfg.Concurrency.AWSPCAIssuer,
+		Namespaces:              controllers.WatchedNamespaces(cfg.Namespaces),
+		AllowAmbientCredentials: cfg.AllowAmbientCredentials,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AWSPCAIssuer")
 		os.Exit(1)
 	}
 
+	if err = (&controllers.AWSPCAIssuedCertificateReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("AWSPCAIssuedCertificate"),
+		Clock:    clock.RealClock{},
+		Recorder: mgr.GetEventRecorderFor("awspcaissuedcertificate-controller"),
+
+		Context:                 ctx,
+		AWSTimeout:              cfg.AWSTimeout.Duration,
+		MaxConcurrentReconciles: cfg.Concurrency.AWSPCAIssuedCertificate,
+		Namespaces:              controllers.WatchedNamespaces(cfg.Namespaces),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AWSPCAIssuedCertificate")
+		os.Exit(1)
+	}
+
+	auditor, err := audit.NewSink(cfg.AuditSink, mgr.GetClient())
+	if err != nil {
+		setupLog.Error(err, "unable to create audit sink")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.CertificateRequestReconciler{
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("CertificateRequest"),
 		Recorder: mgr.GetEventRecorderFor("certificaterequests-controller"),
+
+		DisableApprovalCheck:    cfg.DisableApprovalCheck,
+		Audit:                   auditor,
+		Context:                 ctx,
+		AWSTimeout:              cfg.AWSTimeout.Duration,
+		MaxConcurrentReconciles: cfg.Concurrency.CertificateRequest,
+		Namespaces:              controllers.WatchedNamespaces(cfg.Namespaces),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CertificateRequest")
 		os.Exit(1)
 	}
 
+	if cfg.Webhook.Enable {
+		if err = (&awspcav1beta1.AWSPCAIssuer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSPCAIssuer", "version", "v1beta1")
+			os.Exit(1)
+		}
+		if err = (&awspcav1alpha2.AWSPCAIssuer{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSPCAIssuer", "version", "v1alpha2")
+			os.Exit(1)
+		}
+		if err = (&awspcav1beta1.AWSPCAIssuedCertificate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSPCAIssuedCertificate")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(stop); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// newLogger returns the logger of the given level: error, info or debug.
+// The debug level includes the verbose logs of the controllers.
+func newLogger(level string) logr.Logger {
+	lvl := uzap.NewAtomicLevelAt(uzap.DebugLevel - 3)
+	switch level {
+	case "error":
+		lvl.SetLevel(uzap.ErrorLevel)
+	case "info":
+		lvl.SetLevel(uzap.InfoLevel)
+	}
+	return zap.New(zap.UseDevMode(true), zap.Level(&lvl))
+}
+
+// stringSlice is a flag of comma separated strings.
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// float32Value is a float32 flag.
+type float32Value float32
+
+func (f *float32Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+func (f *float32Value) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return fmt.Errorf("invalid float32 %q: %v", value, err)
+	}
+	*f = float32Value(v)
+	return nil
+}
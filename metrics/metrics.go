@@ -0,0 +1,216 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the Prometheus metrics exposed by the AWSPCA
+// issuer. All metrics are registered on the controller-runtime registry, so
+// they are served by the manager on the address given by --metrics-addr.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "awspca_issuer"
+
+var (
+	// IssuanceAttempts counts the certificate requests sent to a provisioner
+	// for signing.
+	IssuanceAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "issuance_attempts_total",
+		Help:      "Total number of certificate requests sent to AWS Private CA for signing.",
+	}, []string{"namespace", "issuer"})
+
+	// IssuanceSuccesses counts the certificate requests that were signed.
+	IssuanceSuccesses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "issuance_successes_total",
+		Help:      "Total number of certificates issued by AWS Private CA.",
+	}, []string{"namespace", "issuer"})
+
+	// IssuanceFailures counts the certificate requests that could not be
+	// signed, partitioned by AWS error code.
+	IssuanceFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "issuance_failures_total",
+		Help:      "Total number of failed certificate issuances, by AWS error code.",
+	}, []string{"namespace", "issuer", "code"})
+
+	// AWSRequestDuration observes the latency of the AWS Private CA API calls.
+	AWSRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "aws_request_duration_seconds",
+		Help:      "Latency of AWS Private CA API calls, by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"operation"})
+
+	// PendingDuration observes the time between the creation of a
+	// CertificateRequest and its issuance.
+	PendingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "certificaterequest_pending_duration_seconds",
+		Help:      "Time a CertificateRequest spent pending before its certificate was issued.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"namespace", "issuer"})
+
+	// IssuerReady reports 1 if an AWSPCAIssuer is Ready and 0 otherwise.
+	IssuerReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "issuer_ready",
+		Help:      "Whether an AWSPCAIssuer is ready to sign certificates (1) or not (0).",
+	}, []string{"namespace", "issuer"})
+
+	caExpiry = newCAExpiryCollector()
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		IssuanceAttempts,
+		IssuanceSuccesses,
+		IssuanceFailures,
+		AWSRequestDuration,
+		PendingDuration,
+		IssuerReady,
+		caExpiry,
+	)
+}
+
+// ObserveAWSRequest records the latency of an AWS Private CA API call that
+// started at the given time.
+func ObserveAWSRequest(operation string, start time.Time) {
+	AWSRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// SetIssuerReady sets the readiness gauge of the given issuer.
+func SetIssuerReady(namespace, name string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	IssuerReady.WithLabelValues(namespace, name).Set(value)
+}
+
+// DeleteIssuer removes the per-issuer gauges of an issuer that no longer
+// exists, and the expiry of the private CAs only used by that issuer.
+func DeleteIssuer(namespace, name string) {
+	IssuerReady.DeleteLabelValues(namespace, name)
+	caExpiry.retain(issuerKey(namespace, name), nil)
+}
+
+// SetCAExpiry records the NotAfter time of the certificate of a private CA
+// of the given issuer.
+func SetCAExpiry(namespace, name, arn string, notAfter time.Time) {
+	caExpiry.set(issuerKey(namespace, name), arn, notAfter)
+}
+
+// RetainCAs forgets the expiry of the private CAs of the given issuer that
+// are not in arns, e.g. after a CA is removed from the issuer spec. A CA
+// stays reported as long as another issuer uses it.
+func RetainCAs(namespace, name string, arns []string) {
+	caExpiry.retain(issuerKey(namespace, name), arns)
+}
+
+func issuerKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// ErrorCode returns the AWS error code of the given error, or "Unknown" if
+// the error did not come from an AWS API call.
+func ErrorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return "Unknown"
+}
+
+// caExpiryCollector reports the seconds until expiry of every private CA
+// used by an issuer. The value is computed when scraped so it stays current
+// between reconciles of the issuers.
+type caExpiryCollector struct {
+	desc *prometheus.Desc
+	mu   sync.RWMutex
+	// notAfter holds the NotAfter time of the CAs of each issuer, by ARN.
+	notAfter map[string]map[string]time.Time
+}
+
+func newCAExpiryCollector() *caExpiryCollector {
+	return &caExpiryCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "ca_expiry_seconds"),
+			"Seconds until the certificate of an AWS Private CA expires.",
+			[]string{"arn"}, nil,
+		),
+		notAfter: make(map[string]map[string]time.Time),
+	}
+}
+
+func (c *caExpiryCollector) set(issuer, arn string, notAfter time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cas, ok := c.notAfter[issuer]
+	if !ok {
+		cas = make(map[string]time.Time)
+		c.notAfter[issuer] = cas
+	}
+	cas[arn] = notAfter
+}
+
+func (c *caExpiryCollector) retain(issuer string, arns []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cas, ok := c.notAfter[issuer]
+	if !ok {
+		return
+	}
+	keep := make(map[string]bool, len(arns))
+	for _, arn := range arns {
+		keep[arn] = true
+	}
+	for arn := range cas {
+		if !keep[arn] {
+			delete(cas, arn)
+		}
+	}
+	if len(cas) == 0 {
+		delete(c.notAfter, issuer)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *caExpiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *caExpiryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	reported := make(map[string]bool)
+	for _, cas := range c.notAfter {
+		for arn, notAfter := range cas {
+			if reported[arn] {
+				continue
+			}
+			reported[arn] = true
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue,
+				time.Until(notAfter).Seconds(), arn)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	testCAArn1 = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555"
+	testCAArn2 = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/66666666-7777-8888-9999-000000000000"
+)
+
+// collectCAExpiry returns the seconds until expiry reported by the collector,
+// by ARN.
+func collectCAExpiry(t *testing.T, c prometheus.Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+
+	values := make(map[string]float64)
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		values[pb.GetLabel()[0].GetValue()] = pb.GetGauge().GetValue()
+	}
+	return values
+}
+
+func arns(values map[string]float64) []string {
+	var keys []string
+	for arn := range values {
+		keys = append(keys, arn)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestCAExpiryCollector(t *testing.T) {
+	c := newCAExpiryCollector()
+	notAfter := time.Now().Add(time.Hour)
+	c.set("default/issuer-1", testCAArn1, notAfter)
+	c.set("default/issuer-1", testCAArn2, notAfter)
+	c.set("default/issuer-2", testCAArn1, notAfter)
+
+	values := collectCAExpiry(t, c)
+	if got := arns(values); len(got) != 2 || got[0] != testCAArn1 || got[1] != testCAArn2 {
+		t.Fatalf("collected ARNs = %v, want each CA once", got)
+	}
+	if v := values[testCAArn1]; v <= 3500 || v > 3600 {
+		t.Errorf("ca_expiry_seconds = %v, want about one hour", v)
+	}
+
+	// Removing a CA from issuer-1 keeps it reported while issuer-2 uses it.
+	c.retain("default/issuer-1", []string{testCAArn2})
+	if got := arns(collectCAExpiry(t, c)); len(got) != 2 {
+		t.Errorf("collected ARNs after retain = %v, want both CAs", got)
+	}
+
+	c.retain("default/issuer-2", nil)
+	if got := arns(collectCAExpiry(t, c)); len(got) != 1 || got[0] != testCAArn2 {
+		t.Errorf("collected ARNs after deleting issuer-2 = %v, want %v", got, []string{testCAArn2})
+	}
+
+	c.retain("default/issuer-1", nil)
+	if got := collectCAExpiry(t, c); len(got) != 0 {
+		t.Errorf("collected ARNs after deleting all issuers = %v, want none", arns(got))
+	}
+	if len(c.notAfter) != 0 {
+		t.Errorf("collector still holds issuers %v", c.notAfter)
+	}
+}
+
+func TestDeleteIssuer(t *testing.T) {
+	SetIssuerReady("default", "deleted", true)
+	SetCAExpiry("default", "deleted", testCAArn1, time.Now().Add(time.Hour))
+	if v := testutil.ToFloat64(IssuerReady.WithLabelValues("default", "deleted")); v != 1 {
+		t.Errorf("issuer_ready = %v, want 1", v)
+	}
+
+	DeleteIssuer("default", "deleted")
+	if _, ok := collectCAExpiry(t, caExpiry)[testCAArn1]; ok {
+		t.Errorf("ca_expiry_seconds still reported for %s after the issuer deletion", testCAArn1)
+	}
+	if IssuerReady.DeleteLabelValues("default", "deleted") {
+		t.Error("issuer_ready still reported after the issuer deletion")
+	}
+}
+
+func TestSetIssuerReady(t *testing.T) {
+	defer DeleteIssuer("default", "ready")
+	SetIssuerReady("default", "ready", false)
+	if v := testutil.ToFloat64(IssuerReady.WithLabelValues("default", "ready")); v != 0 {
+		t.Errorf("issuer_ready = %v, want 0", v)
+	}
+	SetIssuerReady("default", "ready", true)
+	if v := testutil.ToFloat64(IssuerReady.WithLabelValues("default", "ready")); v != 1 {
+		t.Errorf("issuer_ready = %v, want 1", v)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{awserr.New("ThrottlingException", "Rate exceeded", nil), "ThrottlingException"},
+		{errors.New("failed"), "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := ErrorCode(tt.err); got != tt.want {
+			t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
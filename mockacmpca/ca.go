@@ -0,0 +1,325 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockacmpca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// caValidity is the validity of the certificates of the mock CAs.
+const caValidity = 10 * 365 * 24 * time.Hour
+
+// certificateAuthority is a local root CA.
+type certificateAuthority struct {
+	arn  string
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	pem  []byte
+}
+
+// issuedCertificate is a certificate issued by a CA.
+type issuedCertificate struct {
+	caArn    string
+	cert     *x509.Certificate
+	pem      []byte
+	issuedAt time.Time
+	revoked  bool
+}
+
+func newCertificateAuthority(arn string, now time.Time) (*certificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Mock AWS Private CA " + arn[strings.LastIndex(arn, "/")+1:]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &certificateAuthority{
+		arn:  arn,
+		key:  key,
+		cert: cert,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+// newSerialNumber returns a random 128 bits serial number.
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// The inputs and outputs of the operations, with the names of the JSON
+// protocol. Timestamps are in seconds since the epoch.
+
+type validity struct {
+	Type  string `json:"Type"`
+	Value int64  `json:"Value"`
+}
+
+type subject struct {
+	CommonName         string `json:"CommonName,omitempty"`
+	Country            string `json:"Country,omitempty"`
+	Organization       string `json:"Organization,omitempty"`
+	OrganizationalUnit string `json:"OrganizationalUnit,omitempty"`
+}
+
+type issueCertificateInput struct {
+	ApiPassthrough *struct {
+		Subject *subject `json:"Subject"`
+	} `json:"ApiPassthrough"`
+	CertificateAuthorityArn string   `json:"CertificateAuthorityArn"`
+	Csr                     []byte   `json:"Csr"`
+	SigningAlgorithm        string   `json:"SigningAlgorithm"`
+	TemplateArn             string   `json:"TemplateArn"`
+	Validity                validity `json:"Validity"`
+}
+
+type getCertificateInput struct {
+	CertificateArn          string `json:"CertificateArn"`
+	CertificateAuthorityArn string `json:"CertificateAuthorityArn"`
+}
+
+type certificateAuthorityInput struct {
+	CertificateAuthorityArn string `json:"CertificateAuthorityArn"`
+}
+
+type revokeCertificateInput struct {
+	CertificateAuthorityArn string `json:"CertificateAuthorityArn"`
+	CertificateSerial       string `json:"CertificateSerial"`
+	RevocationReason        string `json:"RevocationReason"`
+}
+
+// lookupCA returns the CA with the given ARN, which must be active to issue
+// certificates.
+func (s *Server) lookupCA(arn string, active bool) (*certificateAuthority, *apiError) {
+	ca, ok := s.cas[arn]
+	if !ok {
+		return nil, errorf("ResourceNotFoundException", "Could not find certificate authority %s", arn)
+	}
+	if active && s.isDisabled(arn) {
+		return nil, errorf("InvalidStateException", "The certificate authority %s is not in the correct state to issue certificates", arn)
+	}
+	return ca, nil
+}
+
+func (s *Server) isDisabled(arn string) bool {
+	for _, disabled := range s.config.DisabledCAs {
+		if disabled == arn {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) issueCertificate(in *issueCertificateInput) (interface{}, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ca, err := s.lookupCA(in.CertificateAuthorityArn, true)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(in.Csr)
+	if block == nil {
+		return nil, errorf("MalformedCSRException", "The CSR is not PEM encoded")
+	}
+	csr, perr := x509.ParseCertificateRequest(block.Bytes)
+	if perr == nil {
+		perr = csr.CheckSignature()
+	}
+	if perr != nil {
+		return nil, errorf("MalformedCSRException", "Invalid CSR: %v", perr)
+	}
+
+	if in.Validity.Value <= 0 {
+		return nil, errorf("ValidationException", "The validity must be positive")
+	}
+	now := s.now()
+	var notAfter time.Time
+	switch in.Validity.Type {
+	case "DAYS":
+		notAfter = now.AddDate(0, 0, int(in.Validity.Value))
+	case "MONTHS":
+		notAfter = now.AddDate(0, int(in.Validity.Value), 0)
+	case "YEARS":
+		notAfter = now.AddDate(int(in.Validity.Value), 0, 0)
+	default:
+		return nil, errorf("ValidationException", "Unsupported validity type %q", in.Validity.Type)
+	}
+	if notAfter.After(ca.cert.NotAfter) {
+		return nil, errorf("ValidationException", "The certificate cannot be valid beyond the certificate authority")
+	}
+
+	name := csr.Subject
+	if in.ApiPassthrough != nil && in.ApiPassthrough.Subject != nil {
+		subj := in.ApiPassthrough.Subject
+		name = pkix.Name{CommonName: subj.CommonName}
+		if subj.Country != "" {
+			name.Country = []string{subj.Country}
+		}
+		if subj.Organization != "" {
+			name.Organization = []string{subj.Organization}
+		}
+		if subj.OrganizationalUnit != "" {
+			name.OrganizationalUnit = []string{subj.OrganizationalUnit}
+		}
+	}
+
+	serial, serr := newSerialNumber()
+	if serr != nil {
+		return nil, &apiError{status: http.StatusInternalServerError, code: "InternalFailure", message: serr.Error()}
+	}
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        name,
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		EmailAddresses: csr.EmailAddresses,
+		URIs:           csr.URIs,
+		NotBefore:      now.Add(-time.Minute),
+		NotAfter:       notAfter,
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, cerr := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+	if cerr != nil {
+		return nil, errorf("MalformedCSRException", "Failed to sign the CSR: %v", cerr)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	arn := fmt.Sprintf("%s/certificate/%s", ca.arn, serial.Text(16))
+	s.certs[arn] = &issuedCertificate{
+		caArn:    ca.arn,
+		cert:     cert,
+		pem:      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		issuedAt: now,
+	}
+	return map[string]string{"CertificateArn": arn}, nil
+}
+
+func (s *Server) getCertificate(in *getCertificateInput) (interface{}, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ca, err := s.lookupCA(in.CertificateAuthorityArn, false)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := s.certs[in.CertificateArn]
+	if !ok || cert.caArn != ca.arn {
+		return nil, errorf("ResourceNotFoundException", "Could not find certificate %s", in.CertificateArn)
+	}
+	if s.now().Before(cert.issuedAt.Add(s.config.IssueDelay.Duration)) {
+		return nil, errorf("RequestInProgressException", "The certificate %s is being issued", in.CertificateArn)
+	}
+	return map[string]string{
+		"Certificate":      strings.TrimSpace(string(cert.pem)),
+		"CertificateChain": strings.TrimSpace(string(ca.pem)),
+	}, nil
+}
+
+func (s *Server) describeCertificateAuthority(in *certificateAuthorityInput) (interface{}, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ca, err := s.lookupCA(in.CertificateAuthorityArn, false)
+	if err != nil {
+		return nil, err
+	}
+	status := "ACTIVE"
+	if s.isDisabled(ca.arn) {
+		status = "DISABLED"
+	}
+	return map[string]interface{}{
+		"CertificateAuthority": map[string]interface{}{
+			"Arn":       ca.arn,
+			"Status":    status,
+			"Type":      "ROOT",
+			"CreatedAt": ca.cert.NotBefore.Unix(),
+			"NotBefore": ca.cert.NotBefore.Unix(),
+			"NotAfter":  ca.cert.NotAfter.Unix(),
+			"CertificateAuthorityConfiguration": map[string]interface{}{
+				"KeyAlgorithm":     "EC_prime256v1",
+				"SigningAlgorithm": "SHA256WITHECDSA",
+				"Subject":          subject{CommonName: ca.cert.Subject.CommonName},
+			},
+		},
+	}, nil
+}
+
+func (s *Server) getCertificateAuthorityCertificate(in *certificateAuthorityInput) (interface{}, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ca, err := s.lookupCA(in.CertificateAuthorityArn, false)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"Certificate": strings.TrimSpace(string(ca.pem))}, nil
+}
+
+func (s *Server) revokeCertificate(in *revokeCertificateInput) (interface{}, *apiError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ca, err := s.lookupCA(in.CertificateAuthorityArn, false)
+	if err != nil {
+		return nil, err
+	}
+	serial, ok := new(big.Int).SetString(strings.Replace(in.CertificateSerial, ":", "", -1), 16)
+	if !ok {
+		return nil, errorf("InvalidArgsException", "Invalid certificate serial %q", in.CertificateSerial)
+	}
+	for _, cert := range s.certs {
+		if cert.caArn != ca.arn || cert.cert.SerialNumber.Cmp(serial) != 0 {
+			continue
+		}
+		if cert.revoked {
+			return nil, errorf("RequestAlreadyProcessedException", "The certificate %s has already been revoked", in.CertificateSerial)
+		}
+		cert.revoked = true
+		return struct{}{}, nil
+	}
+	return nil, errorf("ResourceNotFoundException", "Could not find certificate %s", in.CertificateSerial)
+}
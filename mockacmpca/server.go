@@ -0,0 +1,235 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mockacmpca implements a mock of the AWS Private CA API for tests.
+// The server speaks the JSON protocol of the AWS SDK and signs the
+// certificates with local CAs, so that the controller can be tested without
+// AWS. Faults such as throttling, latency and disabled CAs can be injected.
+package mockacmpca
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// targetPrefix is the prefix of the X-Amz-Target header of the AWS Private
+// CA operations.
+const targetPrefix = "ACMPrivateCA."
+
+// ConfigPath is the path of the endpoint to read and change the Config of a
+// running server, with GET and PUT requests.
+const ConfigPath = "/mock/config"
+
+// Config holds the faults injected by the server.
+type Config struct {
+	// ThrottleRate is the fraction of the requests, between 0 and 1, that
+	// fail with a ThrottlingException.
+	ThrottleRate float64 `json:"throttleRate,omitempty"`
+	// Latency is added to every request.
+	Latency metav1.Duration `json:"latency,omitempty"`
+	// IssueDelay is how long GetCertificate fails with a
+	// RequestInProgressException after a certificate is issued.
+	IssueDelay metav1.Duration `json:"issueDelay,omitempty"`
+	// DisabledCAs are the ARNs of the CAs with the DISABLED status, which
+	// fail to issue certificates with an InvalidStateException.
+	DisabledCAs []string `json:"disabledCAs,omitempty"`
+	// AccessKeyID is the only access key accepted if set, the requests
+	// signed with another key fail with an UnrecognizedClientException.
+	AccessKeyID string `json:"accessKeyID,omitempty"`
+}
+
+// Server is a mock AWS Private CA API server.
+type Server struct {
+	mu     sync.Mutex
+	config Config
+	cas    map[string]*certificateAuthority
+	// certs are the issued certificates by ARN.
+	certs map[string]*issuedCertificate
+
+	// now and random can be replaced in tests.
+	now    func() time.Time
+	random func() float64
+}
+
+// NewServer returns a server with a root CA for each of the given ARNs.
+func NewServer(caArns []string, config Config) (*Server, error) {
+	s := &Server{
+		config: config,
+		cas:    make(map[string]*certificateAuthority),
+		certs:  make(map[string]*issuedCertificate),
+		now:    time.Now,
+		random: rand.Float64,
+	}
+	for _, arn := range caArns {
+		ca, err := newCertificateAuthority(arn, s.now())
+		if err != nil {
+			return nil, err
+		}
+		s.cas[arn] = ca
+	}
+	return s, nil
+}
+
+// Config returns the faults injected by the server.
+func (s *Server) Config() Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetConfig changes the faults injected by the server.
+func (s *Server) SetConfig(config Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+}
+
+// IsRevoked returns true if the certificate with the given ARN has been
+// revoked.
+func (s *Server) IsRevoked(certificateArn string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cert, ok := s.certs[certificateArn]
+	return ok && cert.revoked
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ConfigPath {
+		s.serveConfig(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	config := s.Config()
+	time.Sleep(config.Latency.Duration)
+	if config.ThrottleRate > 0 && s.random() < config.ThrottleRate {
+		writeError(w, &apiError{status: http.StatusBadRequest, code: "ThrottlingException", message: "Rate exceeded"})
+		return
+	}
+	if config.AccessKeyID != "" && accessKeyID(r) != config.AccessKeyID {
+		writeError(w, errorf("UnrecognizedClientException", "The security token included in the request is invalid."))
+		return
+	}
+
+	var out interface{}
+	var err *apiError
+	switch op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix); op {
+	case "IssueCertificate":
+		var in issueCertificateInput
+		if err = decode(r, &in); err == nil {
+			out, err = s.issueCertificate(&in)
+		}
+	case "GetCertificate":
+		var in getCertificateInput
+		if err = decode(r, &in); err == nil {
+			out, err = s.getCertificate(&in)
+		}
+	case "DescribeCertificateAuthority":
+		var in certificateAuthorityInput
+		if err = decode(r, &in); err == nil {
+			out, err = s.describeCertificateAuthority(&in)
+		}
+	case "GetCertificateAuthorityCertificate":
+		var in certificateAuthorityInput
+		if err = decode(r, &in); err == nil {
+			out, err = s.getCertificateAuthorityCertificate(&in)
+		}
+	case "RevokeCertificate":
+		var in revokeCertificateInput
+		if err = decode(r, &in); err == nil {
+			out, err = s.revokeCertificate(&in)
+		}
+	default:
+		err = &apiError{status: http.StatusBadRequest, code: "UnknownOperationException", message: fmt.Sprintf("unsupported operation %q", op)}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// accessKeyID returns the access key of the signature of the request, from
+// an Authorization header such as "AWS4-HMAC-SHA256
+// Credential=<key>/<date>/<region>/<service>/aws4_request, ...".
+func accessKeyID(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "Credential=")
+	if i < 0 {
+		return ""
+	}
+	credential := auth[i+len("Credential="):]
+	if j := strings.Index(credential, "/"); j >= 0 {
+		return credential[:j]
+	}
+	return ""
+}
+
+// serveConfig returns the config of the server on GET, and replaces it on
+// PUT.
+func (s *Server) serveConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var config Config
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.SetConfig(config)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Config())
+}
+
+// apiError is an error returned by the API, with the code of the exception.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func errorf(code, format string, args ...interface{}) *apiError {
+	return &apiError{status: http.StatusBadRequest, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(err.status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": err.code, "message": err.message})
+}
+
+func decode(r *http.Request, in interface{}) *apiError {
+	if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+		return errorf("SerializationException", "invalid request: %v", err)
+	}
+	return nil
+}
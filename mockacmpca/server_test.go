@@ -0,0 +1,212 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockacmpca
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/acmpca"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testCAArn     = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555"
+	disabledCAArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/66666666-7777-8888-9999-000000000000"
+)
+
+// newClient returns an AWS Private CA client of the given server. The SDK
+// does not retry, so that the injected faults are returned.
+func newClient(t *testing.T, server *Server) *acmpca.ACMPCA {
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(ts.URL),
+		Credentials: credentials.NewStaticCredentials("access", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acmpca.New(sess)
+}
+
+func newCSR(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"www.example.com"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func errorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func TestServer(t *testing.T) {
+	server, err := NewServer([]string{testCAArn, disabledCAArn}, Config{DisabledCAs: []string{disabledCAArn}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newClient(t, server)
+
+	issue := func(caArn string) (*acmpca.IssueCertificateOutput, error) {
+		return svc.IssueCertificate(&acmpca.IssueCertificateInput{
+			CertificateAuthorityArn: aws.String(caArn),
+			Csr:                     newCSR(t),
+			SigningAlgorithm:        aws.String(acmpca.SigningAlgorithmSha256withecdsa),
+			Validity:                &acmpca.Validity{Type: aws.String(acmpca.ValidityPeriodTypeDays), Value: aws.Int64(30)},
+			IdempotencyToken:        aws.String("token"),
+		})
+	}
+
+	issued, err := issue(testCAArn)
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	got, err := svc.GetCertificate(&acmpca.GetCertificateInput{
+		CertificateArn:          issued.CertificateArn,
+		CertificateAuthorityArn: aws.String(testCAArn),
+	})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	block, _ := pem.Decode([]byte(aws.StringValue(got.Certificate)))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("GetCertificate() returned an invalid certificate: %v", err)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "www.example.com" {
+		t.Errorf("certificate SANs = %v, want [www.example.com]", cert.DNSNames)
+	}
+
+	caCert, err := svc.GetCertificateAuthorityCertificate(&acmpca.GetCertificateAuthorityCertificateInput{CertificateAuthorityArn: aws.String(testCAArn)})
+	if err != nil {
+		t.Fatalf("GetCertificateAuthorityCertificate() error = %v", err)
+	}
+	if aws.StringValue(caCert.Certificate) != aws.StringValue(got.CertificateChain) {
+		t.Errorf("the chain of the certificate is not the CA certificate")
+	}
+
+	desc, err := svc.DescribeCertificateAuthority(&acmpca.DescribeCertificateAuthorityInput{CertificateAuthorityArn: aws.String(disabledCAArn)})
+	if err != nil {
+		t.Fatalf("DescribeCertificateAuthority() error = %v", err)
+	}
+	if aws.StringValue(desc.CertificateAuthority.Status) != acmpca.CertificateAuthorityStatusDisabled || desc.CertificateAuthority.NotAfter == nil {
+		t.Errorf("DescribeCertificateAuthority() = %v, want a DISABLED CA with a NotAfter time", desc)
+	}
+	if _, err := issue(disabledCAArn); errorCode(err) != acmpca.ErrCodeInvalidStateException {
+		t.Errorf("IssueCertificate() with a disabled CA error = %v, want %s", err, acmpca.ErrCodeInvalidStateException)
+	}
+	if _, err := issue("arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/unknown"); errorCode(err) != acmpca.ErrCodeResourceNotFoundException {
+		t.Errorf("IssueCertificate() with an unknown CA error = %v, want %s", err, acmpca.ErrCodeResourceNotFoundException)
+	}
+
+	revoke := func() error {
+		_, err := svc.RevokeCertificate(&acmpca.RevokeCertificateInput{
+			CertificateAuthorityArn: aws.String(testCAArn),
+			CertificateSerial:       aws.String(cert.SerialNumber.Text(16)),
+			RevocationReason:        aws.String(acmpca.RevocationReasonKeyCompromise),
+		})
+		return err
+	}
+	if err := revoke(); err != nil {
+		t.Fatalf("RevokeCertificate() error = %v", err)
+	}
+	if !server.IsRevoked(aws.StringValue(issued.CertificateArn)) {
+		t.Error("the certificate was not revoked")
+	}
+	if err := revoke(); errorCode(err) != acmpca.ErrCodeRequestAlreadyProcessedException {
+		t.Errorf("RevokeCertificate() twice error = %v, want %s", err, acmpca.ErrCodeRequestAlreadyProcessedException)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server, err := NewServer([]string{testCAArn}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	server.now = func() time.Time { return now }
+	svc := newClient(t, server)
+
+	// Certificates are not available before the issue delay.
+	server.SetConfig(Config{IssueDelay: metav1.Duration{Duration: time.Minute}})
+	issued, err := svc.IssueCertificate(&acmpca.IssueCertificateInput{
+		CertificateAuthorityArn: aws.String(testCAArn),
+		Csr:                     newCSR(t),
+		SigningAlgorithm:        aws.String(acmpca.SigningAlgorithmSha256withecdsa),
+		Validity:                &acmpca.Validity{Type: aws.String(acmpca.ValidityPeriodTypeDays), Value: aws.Int64(30)},
+		IdempotencyToken:        aws.String("token"),
+	})
+	if err != nil {
+		t.Fatalf("IssueCertificate() error = %v", err)
+	}
+	get := &acmpca.GetCertificateInput{CertificateArn: issued.CertificateArn, CertificateAuthorityArn: aws.String(testCAArn)}
+	if _, err := svc.GetCertificate(get); errorCode(err) != acmpca.ErrCodeRequestInProgressException {
+		t.Errorf("GetCertificate() error = %v, want %s", err, acmpca.ErrCodeRequestInProgressException)
+	}
+	now = now.Add(time.Minute)
+	if _, err := svc.GetCertificate(get); err != nil {
+		t.Errorf("GetCertificate() after the issue delay error = %v", err)
+	}
+
+	// The config can be changed with the config endpoint.
+	body, _ := json.Marshal(Config{ThrottleRate: 1})
+	req, _ := http.NewRequest(http.MethodPut, svc.Endpoint+ConfigPath, bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT %s error = %v", ConfigPath, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || server.Config().ThrottleRate != 1 {
+		t.Fatalf("PUT %s = %s, config %+v", ConfigPath, resp.Status, server.Config())
+	}
+	if _, err := svc.GetCertificate(get); errorCode(err) != "ThrottlingException" {
+		t.Errorf("GetCertificate() error = %v, want ThrottlingException", err)
+	}
+
+	// Only the configured access key is accepted.
+	server.SetConfig(Config{AccessKeyID: "rotated"})
+	if _, err := svc.GetCertificate(get); errorCode(err) != "UnrecognizedClientException" {
+		t.Errorf("GetCertificate() with another access key error = %v, want UnrecognizedClientException", err)
+	}
+	server.SetConfig(Config{AccessKeyID: "access"})
+	if _, err := svc.GetCertificate(get); err != nil {
+		t.Errorf("GetCertificate() with the access key error = %v", err)
+	}
+}
@@ -21,16 +21,37 @@ import (
 	"encoding/pem"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/acmpca"
-	certmanager "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/metrics"
+	"github.com/awspca-issuer/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/types"
+	"math/rand"
+	"sort"
 	"sync"
+	"time"
 )
 
 var collection = new(sync.Map)
 
+// endpoint is the URL of the AWS Private CA API used instead of the regional
+// endpoints of AWS, if not empty.
+var endpoint string
+
+// SetEndpoint sets the URL of the AWS Private CA API used by all the
+// provisioners instead of the regional endpoints of AWS, e.g. a mock server
+// in tests. It must be called before the provisioners are used.
+func SetEndpoint(url string) {
+	endpoint = url
+}
+
 // AWSPCA implements a AWSCM provisioner in charge of signing certificate
 // requests by calling AWS Private CA API's
 type AWSPCA struct {
@@ -39,17 +60,95 @@ type AWSPCA struct {
 }
 
 type AWSPCAProvisioner struct {
+	// static is true if the AWS calls are signed with accesskey and
+	// secretkey, and false if the default AWS credential chain is used.
+	static    bool
 	accesskey string
 	secretkey string
-	region string
-	arn string
+	cas       []CertificateAuthority
+	options   Options
 }
 
-func NewProvisioner(accesskey string,
-	secretkey string, region string, arn string) (p *AWSPCAProvisioner) {
+// Options configures how a provisioner issues certificates.
+type Options struct {
+	// TemplateArn is the ARN of the AWS Private CA certificate template,
+	// the CA uses EndEntityCertificate/V1 if empty.
+	TemplateArn string
+	// Subject configures the subject of the certificates issued with an API
+	// passthrough template.
+	Subject *Subject
+	// CSRPolicy configures the checks of the certificate requests.
+	CSRPolicy CSRPolicy
+	// SPIFFETrustDomain is the trust domain of the SPIFFE IDs in the
+	// certificate requests, any trust domain is allowed if empty.
+	SPIFFETrustDomain string
+	// Retry configures the retries of the AWS calls and the wait for the
+	// certificates to be issued.
+	Retry RetryOptions
+	// RetiredCAs are the ARNs of the private CAs no longer used by the
+	// provisioner, whose certificates can still be revoked.
+	RetiredCAs []string
+}
+
+// The defaults of RetryOptions.
+const (
+	DefaultMaxRetries      = 3
+	DefaultWaitInterval    = 3 * time.Second
+	DefaultWaitMaxAttempts = 60
+)
+
+// RetryOptions configures how the AWS Private CA calls are retried, and how
+// long the provisioner waits for a certificate to be issued.
+type RetryOptions struct {
+	// MaxRetries is the maximum number of retries of a failed call,
+	// DefaultMaxRetries if nil.
+	MaxRetries *int
+	// MinRetryDelay and MaxRetryDelay bound the exponential backoff, with
+	// jitter, between the retries of a failed call. The defaults of the AWS
+	// SDK are used if zero.
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+	// WaitInterval is the delay between two checks of a certificate being
+	// issued, DefaultWaitInterval if zero.
+	WaitInterval time.Duration
+	// WaitMaxAttempts is the maximum number of checks of a certificate
+	// being issued, DefaultWaitMaxAttempts if zero.
+	WaitMaxAttempts int
+}
+
+// PendingError is returned when a certificate has been issued by AWS Private
+// CA, but is still not available after the wait. It can be retrieved later
+// with Retrieve.
+type PendingError struct {
+	CertificateArn string
+	CAArn          string
+	Err            error
+}
+
+// Error implements error.
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("certificate %s is still being issued: %v", e.CertificateArn, e.Err)
+}
+
+// CertificateAuthority is an AWS Private CA used by a provisioner.
+type CertificateAuthority struct {
+	Arn    string
+	Region string
+	// Weight is the relative share of the certificates issued by the CA.
+	Weight int32
+	// Draining CAs do not issue certificates.
+	Draining bool
+}
+
+// NewProvisioner returns a provisioner signing certificates with the given
+// private CAs, in order of preference. If static is true the AWS calls are
+// signed with the given access key and secret key, otherwise with the
+// default AWS credential chain of the process and the keys are ignored.
+func NewProvisioner(static bool, accesskey string,
+	secretkey string, cas []CertificateAuthority, options Options) (p *AWSPCAProvisioner) {
 
 	return &AWSPCAProvisioner{
-		accesskey: accesskey, secretkey: secretkey, region: region, arn: arn,
+		static: static, accesskey: accesskey, secretkey: secretkey, cas: cas, options: options,
 	}
 }
 
@@ -68,74 +167,573 @@ func Store(namespacedName types.NamespacedName, provisioner *AWSPCAProvisioner)
 	collection.Store(namespacedName, provisioner)
 }
 
-// Sign sends the certificate requests to the Step CA and returns the signed
-// certificate.
-func (p *AWSPCAProvisioner) Sign(ctx context.Context, cr *certmanager.CertificateRequest) ([]byte, []byte, error) {
+// Delete removes a provisioner and its last verification from the
+// collection by NamespacedName.
+func Delete(namespacedName types.NamespacedName) {
+	collection.Delete(namespacedName)
+	verifications.Delete(namespacedName)
+}
+
+// verifications holds the last Verification of the provisioners of the
+// collection by NamespacedName. They are kept apart, so that they survive
+// the replacement of a provisioner when its issuer is updated.
+var verifications = new(sync.Map)
+
+// Verification is the result of the last check of the private CAs of a
+// provisioner with AWS.
+type Verification struct {
+	Time time.Time `json:"time"`
+	// Error is the reason of the failure of the check, empty if the check
+	// succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// SetVerification records the result of the check of the private CAs of a
+// provisioner with AWS at the given time, a nil error meaning success.
+func SetVerification(namespacedName types.NamespacedName, at time.Time, err error) {
+	v := Verification{Time: at}
+	if err != nil {
+		v.Error = err.Error()
+	}
+	verifications.Store(namespacedName, v)
+}
+
+// Info describes a provisioner of the collection, without its credentials.
+type Info struct {
+	Namespace              string                     `json:"namespace"`
+	Name                   string                     `json:"name"`
+	CertificateAuthorities []CertificateAuthorityInfo `json:"certificateAuthorities"`
+	TemplateArn            string                     `json:"templateArn,omitempty"`
+	// Verification is nil until the private CAs of the provisioner have
+	// been checked.
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+// CertificateAuthorityInfo describes a private CA of a provisioner.
+type CertificateAuthorityInfo struct {
+	Arn      string `json:"arn"`
+	Region   string `json:"region"`
+	Weight   int32  `json:"weight,omitempty"`
+	Draining bool   `json:"draining,omitempty"`
+}
+
+// List returns the provisioners of the collection, sorted by namespace and
+// name.
+func List() []Info {
+	var infos []Info
+	collection.Range(func(k, v interface{}) bool {
+		namespacedName, p := k.(types.NamespacedName), v.(*AWSPCAProvisioner)
+		info := Info{
+			Namespace:   namespacedName.Namespace,
+			Name:        namespacedName.Name,
+			TemplateArn: p.options.TemplateArn,
+		}
+		for _, ca := range p.cas {
+			info.CertificateAuthorities = append(info.CertificateAuthorities, CertificateAuthorityInfo{
+				Arn: ca.Arn, Region: ca.Region, Weight: ca.Weight, Draining: ca.Draining,
+			})
+		}
+		if v, ok := verifications.Load(namespacedName); ok {
+			verification := v.(Verification)
+			info.Verification = &verification
+		}
+		infos = append(infos, info)
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Namespace != infos[j].Namespace {
+			return infos[i].Namespace < infos[j].Namespace
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Certificate is a certificate issued by an AWS Private CA.
+type Certificate struct {
+	// PEM is the PEM encoded certificate followed by its chain.
+	PEM []byte
+	// Arn is the ARN of the certificate in AWS Private CA.
+	Arn string
+	// CAArn is the ARN of the private CA that issued the certificate.
+	CAArn string
+
+	// SerialNumber, SANs and validity of the leaf certificate, only set if
+	// it could be parsed. The serial number is in hexadecimal.
+	SerialNumber string
+	SANs         []string
+	NotBefore    time.Time
+	NotAfter     time.Time
+}
+
+// newCertificate returns the certificate with the given PEM data and the
+// details of its leaf certificate.
+func newCertificate(data []byte, arn, caArn string) *Certificate {
+	cert := &Certificate{PEM: data, Arn: arn, CAArn: caArn}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return cert
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return cert
+	}
+	cert.SerialNumber = leaf.SerialNumber.Text(16)
+	cert.SANs = sanList(leaf.DNSNames, leaf.IPAddresses, leaf.EmailAddresses, leaf.URIs)
+	cert.NotBefore = leaf.NotBefore
+	cert.NotAfter = leaf.NotAfter
+	return cert
+}
+
+// SignPlan describes how a provisioner signs a certificate request.
+type SignPlan struct {
+	// CertificateAuthorities are the private CAs the request is sent to, in
+	// order, the next one is only used if the previous one is unavailable.
+	CertificateAuthorities []CertificateAuthority
+	// TemplateArn is the ARN of the certificate template, the default
+	// template of the CA is used if empty.
+	TemplateArn string
+	// SANs of the certificate request.
+	SANs []string
+
+	// subject is the subject of the certificate with an API passthrough
+	// template, nil if the subject of the CSR is used.
+	subject *asn1Subject
+}
+
+// Subject returns the subject of the certificate set by the provisioner, or
+// an empty string if the subject of the CSR is used.
+func (p *SignPlan) Subject() string {
+	return p.subject.String()
+}
+
+// Plan checks the certificate request and returns how it would be signed,
+// without sending it to AWS Private CA.
+func (p *AWSPCAProvisioner) Plan(ctx context.Context, cr *certmanager.CertificateRequest) (*SignPlan, error) {
 
 	// decode and check certificate request
-	csr, err := decodeCSR(cr.Spec.CSRPEM)
+	_, span := tracing.Start(ctx, "DecodeCSR", tracing.CertificateRequestUIDKey.String(string(cr.UID)))
+	csr, err := decodeCSR(cr.Spec.Request)
+	tracing.End(span, err)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	sans := append([]string{}, csr.DNSNames...)
-	for _, ip := range csr.IPAddresses {
-		sans = append(sans, ip.String())
+	_, span = tracing.Start(ctx, "CheckCSRPolicy", tracing.CertificateRequestUIDKey.String(string(cr.UID)))
+	err = p.options.CSRPolicy.validate(csr, p.options.SPIFFETrustDomain)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("certificate request rejected: %v", err)
 	}
 
-	subject := csr.Subject.CommonName
-	if subject == "" {
-		subject = generateSubject(sans)
+	plan := &SignPlan{
+		CertificateAuthorities: issuingOrder(p.cas, rand.Int63n),
+		TemplateArn:            p.options.TemplateArn,
+		SANs:                   SubjectAlternativeNames(csr),
 	}
 
-	sess := session.Must(session.NewSession(&aws.Config{
-		MaxRetries: aws.Int(3),
-	}))
+	// The subject can only be set with an API passthrough template, other
+	// templates use the subject of the CSR.
+	if isAPIPassthroughTemplate(p.options.TemplateArn) {
+		plan.subject, err = p.options.Subject.subject(cr, csr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
 
-	svc := acmpca.New(sess, &aws.Config{
-		Region: aws.String(p.region),
-		Credentials:      credentials.NewStaticCredentials(p.accesskey,
-			p.secretkey, ""),
-	})
+// Sign sends the certificate requests to the AWS Private CAs and returns the
+// signed certificate. The active CAs are tried in the order given by
+// issuingOrder, the next one is only used if the previous one is
+// unavailable.
+func (p *AWSPCAProvisioner) Sign(ctx context.Context, cr *certmanager.CertificateRequest) (*Certificate, error) {
+	plan, err := p.Plan(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fmt.Errorf("no active AWS Private CA configured")
+	for _, ca := range plan.CertificateAuthorities {
+		var cert *Certificate
+		cert, err = p.issue(ctx, ca, cr, plan.subject)
+		if err == nil {
+			return cert, nil
+		}
+		// The next CA is not tried once the context is done, the error is
+		// then the cancellation of the request.
+		if ctx.Err() != nil || !isFailoverError(err) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// issue signs the certificate request with the given private CA, and the
+// given subject if not nil. It waits for the certificate to be issued until
+// the context is done.
+func (p *AWSPCAProvisioner) issue(ctx context.Context, ca CertificateAuthority, cr *certmanager.CertificateRequest, subject *asn1Subject) (*Certificate, error) {
+	svc, err := p.client(ca.Region)
+	if err != nil {
+		return nil, err
+	}
 
 	cparams := acmpca.IssueCertificateInput{
-		CertificateAuthorityArn: aws.String(p.arn),
-		SigningAlgorithm: aws.String(acmpca.SigningAlgorithmSha256withrsa),
-		Csr: cr.Spec.CSRPEM,
-		Validity: &acmpca.Validity {
-			Type: aws.String(acmpca.ValidityPeriodTypeDays),
-			Value: aws.Int64(int64(cr.Spec.Duration.Hours()/24)),
+		CertificateAuthorityArn: aws.String(ca.Arn),
+		SigningAlgorithm:        aws.String(acmpca.SigningAlgorithmSha256withrsa),
+		Csr:                     cr.Spec.Request,
+		Validity: &acmpca.Validity{
+			Type:  aws.String(acmpca.ValidityPeriodTypeDays),
+			Value: aws.Int64(int64(cr.Spec.Duration.Hours() / 24)),
 		},
 		IdempotencyToken: aws.String("awspca"),
 	}
+	if p.options.TemplateArn != "" {
+		cparams.TemplateArn = aws.String(p.options.TemplateArn)
+	}
 
-	output, err := svc.IssueCertificate(&cparams)
+	var output *acmpca.IssueCertificateOutput
+	issueCtx, span := tracing.Start(ctx, "IssueCertificate",
+		tracing.CertificateRequestUIDKey.String(string(cr.UID)), tracing.CAArnKey.String(ca.Arn))
+	start := time.Now()
+	if subject != nil {
+		output, err = issueCertificateWithSubject(issueCtx, svc, &cparams, subject)
+	} else {
+		output, err = svc.IssueCertificateWithContext(issueCtx, &cparams)
+	}
+	metrics.ObserveAWSRequest("IssueCertificate", start)
+	if err == nil {
+		span.SetAttributes(tracing.CertificateArnKey.String(aws.StringValue(output.CertificateArn)))
+	}
+	tracing.End(span, err)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// wait for cert
+	return p.retrieve(ctx, svc, ca.Arn, aws.StringValue(output.CertificateArn))
+}
 
-	cparams2 := acmpca.GetCertificateInput {
-		CertificateArn: aws.String(*output.CertificateArn),
-		CertificateAuthorityArn: aws.String(p.arn),
+// Retrieve waits for the certificate with the given ARN, issued by the given
+// private CA, and returns it. It returns a PendingError if the certificate is
+// still not available after the wait.
+func (p *AWSPCAProvisioner) Retrieve(ctx context.Context, caArn, certificateArn string) (*Certificate, error) {
+	region, err := p.region(caArn)
+	if err != nil {
+		return nil, err
 	}
+	svc, err := p.client(region)
+	if err != nil {
+		return nil, err
+	}
+	return p.retrieve(ctx, svc, caArn, certificateArn)
+}
 
-	svc.WaitUntilCertificateIssued(&cparams2)
+// retrieve waits for the given certificate to be issued and returns it with
+// its chain.
+func (p *AWSPCAProvisioner) retrieve(ctx context.Context, svc *acmpca.ACMPCA, caArn, certificateArn string) (*Certificate, error) {
+	input := acmpca.GetCertificateInput{
+		CertificateArn:          aws.String(certificateArn),
+		CertificateAuthorityArn: aws.String(caArn),
+	}
 
-	output2, err2 := svc.GetCertificate(&cparams2)
+	interval := p.options.Retry.WaitInterval
+	if interval == 0 {
+		interval = DefaultWaitInterval
+	}
+	maxAttempts := p.options.Retry.WaitMaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultWaitMaxAttempts
+	}
+	attrs := []attribute.KeyValue{tracing.CAArnKey.String(caArn), tracing.CertificateArnKey.String(certificateArn)}
+	waitCtx, span := tracing.Start(ctx, "WaitUntilCertificateIssued", attrs...)
+	err := svc.WaitUntilCertificateIssuedWithContext(waitCtx, &input,
+		request.WithWaiterDelay(waiterDelay(interval)), request.WithWaiterMaxAttempts(maxAttempts),
+		request.WithWaiterRequestOptions(traceRequest("GetCertificate.Poll", attrs...)))
+	tracing.End(span, err)
+	if err != nil {
+		// The certificate can be retrieved later if the wait ran out of
+		// attempts or of time.
+		if aerr, ok := err.(awserr.Error); ctx.Err() != nil || ok && aerr.Code() == request.WaiterResourceNotReadyErrorCode {
+			return nil, &PendingError{CertificateArn: certificateArn, CAArn: caArn, Err: err}
+		}
+		return nil, err
+	}
 
-	if err2 != nil {
-		return nil, nil, err2
+	getCtx, span := tracing.Start(ctx, "GetCertificate", attrs...)
+	start := time.Now()
+	output, err := svc.GetCertificateWithContext(getCtx, &input)
+	metrics.ObserveAWSRequest("GetCertificate", start)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, err
 	}
 
 	// Encode server certificate with the intermediate
-	certPem := []byte(*output2.Certificate + "\n")
-	chainPem := []byte(*output2.CertificateChain)
+	certPem := []byte(aws.StringValue(output.Certificate) + "\n")
+	chainPem := []byte(aws.StringValue(output.CertificateChain))
 
 	certPem = append(certPem, chainPem...)
-	return certPem, nil, nil
+	return newCertificate(certPem, certificateArn, caArn), nil
+}
+
+// waiterDelay returns the delay between the checks of a certificate being
+// issued, the given interval with a random jitter of up to 20%, so that the
+// certificates issued together are not checked in lockstep.
+func waiterDelay(interval time.Duration) request.WaiterDelay {
+	return func(attempt int) time.Duration {
+		return interval + time.Duration(rand.Int63n(int64(interval)/5+1))
+	}
+}
+
+// traceRequest returns the option tracing an AWS request in a span with the
+// given name and attributes, e.g. every check of the waiter of a
+// certificate.
+func traceRequest(name string, attrs ...attribute.KeyValue) request.Option {
+	return func(r *request.Request) {
+		ctx, span := tracing.Start(r.Context(), name, attrs...)
+		r.SetContext(ctx)
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			tracing.End(span, r.Error)
+		})
+	}
+}
+
+// hasCA returns true if the given private CA is used by the provisioner or is
+// one of its retired CAs.
+func (p *AWSPCAProvisioner) hasCA(caArn string) bool {
+	for _, ca := range p.cas {
+		if ca.Arn == caArn {
+			return true
+		}
+	}
+	for _, arn := range p.options.RetiredCAs {
+		if arn == caArn {
+			return true
+		}
+	}
+	return false
+}
+
+// region returns the region of the given private CA. The CA may no longer be
+// used by the provisioner, its region is then read from its ARN.
+func (p *AWSPCAProvisioner) region(caArn string) (string, error) {
+	for _, ca := range p.cas {
+		if ca.Arn == caArn {
+			return ca.Region, nil
+		}
+	}
+	a, err := arn.Parse(caArn)
+	if err != nil {
+		return "", fmt.Errorf("invalid private CA ARN %q: %v", caArn, err)
+	}
+	return a.Region, nil
+}
+
+// Revoke revokes the certificate with the given serial number, in
+// hexadecimal, issued by the given private CA. The CA must be used by the
+// provisioner or be one of its retired CAs, so that the certificates of the
+// CAs of other issuers cannot be revoked with its credentials. A certificate
+// that has already been revoked is not an error.
+func (p *AWSPCAProvisioner) Revoke(ctx context.Context, caArn, serialNumber, reason string) error {
+	if !p.hasCA(caArn) {
+		return fmt.Errorf("private CA %s is not a current or retired CA of the issuer", caArn)
+	}
+	region, err := p.region(caArn)
+	if err != nil {
+		return err
+	}
+
+	svc, err := p.client(region)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	_, err = svc.RevokeCertificateWithContext(ctx, &acmpca.RevokeCertificateInput{
+		CertificateAuthorityArn: aws.String(caArn),
+		CertificateSerial:       aws.String(serialNumber),
+		RevocationReason:        aws.String(reason),
+	})
+	metrics.ObserveAWSRequest("RevokeCertificate", start)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == acmpca.ErrCodeRequestAlreadyProcessedException {
+		return nil
+	}
+	return err
+}
+
+// DescribeCertificateAuthority returns the details of the given private CA,
+// including its status and the validity of its certificate.
+func (p *AWSPCAProvisioner) DescribeCertificateAuthority(ctx context.Context, ca CertificateAuthority) (*acmpca.CertificateAuthority, error) {
+	svc, err := p.client(ca.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	output, err := svc.DescribeCertificateAuthorityWithContext(ctx, &acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(ca.Arn),
+	})
+	metrics.ObserveAWSRequest("DescribeCertificateAuthority", start)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.CertificateAuthority, nil
+}
+
+// GetCertificateAuthorityCertificate returns the PEM encoded certificate of
+// the given private CA followed by its chain, if any.
+func (p *AWSPCAProvisioner) GetCertificateAuthorityCertificate(ctx context.Context, ca CertificateAuthority) ([]byte, error) {
+	svc, err := p.client(ca.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	output, err := svc.GetCertificateAuthorityCertificateWithContext(ctx, &acmpca.GetCertificateAuthorityCertificateInput{
+		CertificateAuthorityArn: aws.String(ca.Arn),
+	})
+	metrics.ObserveAWSRequest("GetCertificateAuthorityCertificate", start)
+	if err != nil {
+		return nil, err
+	}
+
+	caPem := []byte(aws.StringValue(output.Certificate) + "\n")
+	if output.CertificateChain != nil {
+		caPem = append(caPem, []byte(*output.CertificateChain+"\n")...)
+	}
+	return caPem, nil
+}
+
+// CertificateAuthorities returns the private CAs used by the provisioner, in
+// order of preference.
+func (p *AWSPCAProvisioner) CertificateAuthorities() []CertificateAuthority {
+	return p.cas
+}
+
+// issuingOrder returns the CAs to try for a new certificate: an active CA
+// chosen by weight, or the first active CA if no CA has a weight, followed by
+// the other active CAs in order. The random function returns a number in
+// [0,n).
+func issuingOrder(cas []CertificateAuthority, random func(n int64) int64) []CertificateAuthority {
+	var active []CertificateAuthority
+	var total int64
+	for _, ca := range cas {
+		if !ca.Draining {
+			active = append(active, ca)
+			total += int64(ca.Weight)
+		}
+	}
+	if total == 0 {
+		return active
+	}
+
+	n := random(total)
+	for i, ca := range active {
+		if n < int64(ca.Weight) {
+			order := append([]CertificateAuthority{ca}, active[:i]...)
+			return append(order, active[i+1:]...)
+		}
+		n -= int64(ca.Weight)
+	}
+	return active
+}
+
+// isFailoverError returns true if the given error means that the private CA
+// cannot be reached or cannot issue certificates, so the request may succeed
+// with another CA. Errors caused by the request itself, e.g. an invalid CSR,
+// are not retried.
+func isFailoverError(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	// RequestError is returned by the SDK when the endpoint cannot be reached.
+	case "RequestError", request.ErrCodeResponseTimeout,
+		acmpca.ErrCodeInvalidStateException, acmpca.ErrCodeResourceNotFoundException:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryableError returns true if the given error of Sign or Retrieve is
+// temporary, so the request may succeed later: the context was canceled or
+// its deadline exceeded, AWS throttled the request, or the last private CA
+// tried was unavailable.
+func IsRetryableError(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return true
+	}
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == request.CanceledErrorCode {
+		return true
+	}
+	return request.IsErrorThrottle(err) || isFailoverError(err)
+}
+
+// IsCredentialsError returns true if the given error means that AWS
+// rejected the credentials of the provisioner, or that they do not grant
+// access to the private CA.
+func IsCredentialsError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case "UnrecognizedClientException", "InvalidClientTokenId", "SignatureDoesNotMatch",
+		"IncompleteSignature", "MissingAuthenticationToken", "ExpiredTokenException",
+		"AccessDeniedException", credentials.ErrNoValidProvidersFoundInChain.Code():
+		return true
+	default:
+		return false
+	}
+}
+
+// client returns an AWS Private CA client configured with the provisioner
+// credentials and the given region.
+func (p *AWSPCAProvisioner) client(region string) (*acmpca.ACMPCA, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	retry := p.options.Retry
+	retryer := client.DefaultRetryer{
+		NumMaxRetries: DefaultMaxRetries,
+		MinRetryDelay: retry.MinRetryDelay,
+		MaxRetryDelay: retry.MaxRetryDelay,
+		// The delays of the throttled calls are the same, if set.
+		MinThrottleDelay: retry.MinRetryDelay,
+		MaxThrottleDelay: retry.MaxRetryDelay,
+	}
+	if retry.MaxRetries != nil {
+		retryer.NumMaxRetries = *retry.MaxRetries
+	}
+	config := &aws.Config{
+		Region:  aws.String(region),
+		Retryer: retryer,
+	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	// Without static credentials, the default credential chain of the
+	// session is used. FromIssuer only allows it if the ambient credentials
+	// of the controller are enabled. Empty static credentials are refused
+	// rather than falling back to the default chain.
+	if p.static {
+		if p.accesskey == "" || p.secretkey == "" {
+			return nil, fmt.Errorf("empty AWS access key or secret key")
+		}
+		config.Credentials = credentials.NewStaticCredentials(p.accesskey,
+			p.secretkey, "")
+	}
+
+	return acmpca.New(sess, config), nil
 }
 
 // decodeCSR decodes a certificate request in PEM format and returns the
@@ -158,9 +756,10 @@ func decodeCSR(data []byte) (*x509.CertificateRequest, error) {
 }
 
 // generateSubject returns the first SAN that is not 127.0.0.1 or localhost. The
-// CSRs generated by the Certificate resource have always those SANs. If no SANs
-// are available `awspca-issuer-certificate` will be used as a subject is always
-// required.
+// CSRs generated by the Certificate resource have always those SANs. DNS names
+// and IP addresses are preferred over email addresses and URIs, such as SPIFFE
+// IDs. If no SANs are available `awspca-issuer-certificate` will be used as a
+// subject is always required.
 func generateSubject(sans []string) string {
 	if len(sans) == 0 {
 		return "awspca-issuer-certificate"
@@ -0,0 +1,382 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/acmpca"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	"github.com/awspca-issuer/mockacmpca"
+	"github.com/awspca-issuer/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIssuingOrder(t *testing.T) {
+	primary := CertificateAuthority{Arn: "primary"}
+	secondary := CertificateAuthority{Arn: "secondary"}
+	draining := CertificateAuthority{Arn: "draining", Draining: true}
+	weighted := func(ca CertificateAuthority, weight int32) CertificateAuthority {
+		ca.Weight = weight
+		return ca
+	}
+
+	tests := []struct {
+		name   string
+		cas    []CertificateAuthority
+		random int64
+		want   []CertificateAuthority
+	}{
+		{"ordered", []CertificateAuthority{primary, secondary}, 0, []CertificateAuthority{primary, secondary}},
+		{"draining first", []CertificateAuthority{draining, primary, secondary}, 0, []CertificateAuthority{primary, secondary}},
+		{"only draining", []CertificateAuthority{draining}, 0, nil},
+		{"weighted first", []CertificateAuthority{weighted(primary, 1), weighted(secondary, 3)}, 0, []CertificateAuthority{weighted(primary, 1), weighted(secondary, 3)}},
+		{"weighted second", []CertificateAuthority{weighted(primary, 1), weighted(secondary, 3)}, 1, []CertificateAuthority{weighted(secondary, 3), weighted(primary, 1)}},
+		{"weighted last", []CertificateAuthority{weighted(primary, 1), weighted(secondary, 3)}, 3, []CertificateAuthority{weighted(secondary, 3), weighted(primary, 1)}},
+		{"failover only", []CertificateAuthority{primary, weighted(secondary, 2), draining}, 1, []CertificateAuthority{weighted(secondary, 2), primary}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			random := func(n int64) int64 {
+				if tt.random >= n {
+					t.Fatalf("random value %d out of range [0,%d)", tt.random, n)
+				}
+				return tt.random
+			}
+			if got := issuingOrder(tt.cas, random); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issuingOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notBefore := time.Date(2021, 3, 4, 10, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(24 * time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(0xabc),
+		DNSNames:     []string{"backend.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	chain := []byte("-----BEGIN CERTIFICATE-----\nchain\n-----END CERTIFICATE-----\n")
+
+	got := newCertificate(append(data, chain...), "certificate-arn", "ca-arn")
+	want := &Certificate{
+		PEM:          append(data, chain...),
+		Arn:          "certificate-arn",
+		CAArn:        "ca-arn",
+		SerialNumber: "abc",
+		SANs:         []string{"backend.example.com", "10.0.0.1"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newCertificate() = %+v, want %+v", got, want)
+	}
+
+	// The details are not set if the certificate cannot be parsed.
+	got = newCertificate([]byte("invalid"), "certificate-arn", "ca-arn")
+	if got.Arn != "certificate-arn" || got.SerialNumber != "" || !got.NotAfter.IsZero() {
+		t.Errorf("newCertificate() = %+v, want only the ARNs and PEM set", got)
+	}
+}
+
+func TestSignContext(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+	server, err := mockacmpca.NewServer([]string{caArn}, mockacmpca.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	SetEndpoint(ts.URL)
+	defer SetEndpoint("")
+
+	cr := &certmanager.CertificateRequest{
+		Spec: certmanager.CertificateRequestSpec{
+			Request:  newTestCSR(t),
+			Duration: &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	}
+	p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: caArn, Region: "us-east-1"}}, Options{})
+
+	cert, err := p.Sign(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if cert.CAArn != caArn || !reflect.DeepEqual(cert.SANs, []string{"backend.example.com"}) {
+		t.Errorf("Sign() = %+v, want a certificate of backend.example.com issued by %s", cert, caArn)
+	}
+
+	// The wait for a certificate that is never issued stops at the deadline.
+	server.SetConfig(mockacmpca.Config{IssueDelay: metav1.Duration{Duration: time.Hour}})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := p.Sign(ctx, cr); !isPendingError(err) {
+		t.Errorf("Sign() error = %v, want a PendingError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Sign() returned after %s, want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestSignSpans(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+	server, err := mockacmpca.NewServer([]string{caArn}, mockacmpca.Config{IssueDelay: metav1.Duration{Duration: 50 * time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	SetEndpoint(ts.URL)
+	defer SetEndpoint("")
+
+	exporter := tracetest.NewInMemoryExporter()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	cr := &certmanager.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{UID: "uid"},
+		Spec: certmanager.CertificateRequestSpec{
+			Request:  newTestCSR(t),
+			Duration: &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	}
+	p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: caArn, Region: "us-east-1"}}, Options{
+		Retry: RetryOptions{WaitInterval: 20 * time.Millisecond},
+	})
+	cert, err := p.Sign(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	spans := make(map[string]map[attribute.Key]string)
+	polls := 0
+	for _, s := range exporter.GetSpans() {
+		attrs := make(map[attribute.Key]string)
+		for _, kv := range s.Attributes {
+			attrs[kv.Key] = kv.Value.AsString()
+		}
+		spans[s.Name] = attrs
+		if s.Name == "GetCertificate.Poll" {
+			polls++
+		}
+	}
+	for name, want := range map[string]map[attribute.Key]string{
+		"DecodeCSR":                  {tracing.CertificateRequestUIDKey: "uid"},
+		"CheckCSRPolicy":             {tracing.CertificateRequestUIDKey: "uid"},
+		"IssueCertificate":           {tracing.CertificateRequestUIDKey: "uid", tracing.CAArnKey: caArn, tracing.CertificateArnKey: cert.Arn},
+		"WaitUntilCertificateIssued": {tracing.CAArnKey: caArn, tracing.CertificateArnKey: cert.Arn},
+		"GetCertificate.Poll":        {tracing.CAArnKey: caArn, tracing.CertificateArnKey: cert.Arn},
+		"GetCertificate":             {tracing.CAArnKey: caArn, tracing.CertificateArnKey: cert.Arn},
+	} {
+		if got, ok := spans[name]; !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("span %s attributes = %v, want %v", name, got, want)
+		}
+	}
+	if polls < 2 {
+		t.Errorf("%d GetCertificate.Poll spans, want one for every check of the waiter", polls)
+	}
+}
+
+func TestSignPending(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+	server, err := mockacmpca.NewServer([]string{caArn}, mockacmpca.Config{IssueDelay: metav1.Duration{Duration: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	SetEndpoint(ts.URL)
+	defer SetEndpoint("")
+
+	noRetries := 0
+	p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: caArn, Region: "us-east-1"}}, Options{
+		Retry: RetryOptions{MaxRetries: &noRetries, WaitInterval: 10 * time.Millisecond, WaitMaxAttempts: 2},
+	})
+	cr := &certmanager.CertificateRequest{
+		Spec: certmanager.CertificateRequestSpec{
+			Request:  newTestCSR(t),
+			Duration: &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	}
+
+	// The certificate is not available after the attempts of the waiter.
+	_, err = p.Sign(context.Background(), cr)
+	pending, ok := err.(*PendingError)
+	if !ok {
+		t.Fatalf("Sign() error = %v, want a PendingError", err)
+	}
+	if pending.CAArn != caArn || pending.CertificateArn == "" {
+		t.Errorf("Sign() error = %+v, want the ARNs of the pending certificate", pending)
+	}
+
+	// It is retrieved once issued.
+	server.SetConfig(mockacmpca.Config{})
+	cert, err := p.Retrieve(context.Background(), pending.CAArn, pending.CertificateArn)
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if cert.Arn != pending.CertificateArn || cert.SerialNumber == "" {
+		t.Errorf("Retrieve() = %+v, want the pending certificate", cert)
+	}
+
+	// Throttled calls are not retried without retries.
+	server.SetConfig(mockacmpca.Config{ThrottleRate: 1})
+	if _, err := p.Sign(context.Background(), cr); err == nil || isPendingError(err) || !IsRetryableError(err) {
+		t.Errorf("Sign() error = %v, want a retryable ThrottlingException", err)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{context.Canceled, true},
+		{awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled), true},
+		{awserr.New("ThrottlingException", "rate exceeded", nil), true},
+		{awserr.New("RequestError", "send request failed", nil), true},
+		{awserr.NewRequestFailure(awserr.New("InternalFailure", "", nil), 503, "id"), true},
+		{awserr.New(acmpca.ErrCodeInvalidStateException, "CA is disabled", nil), true},
+		{awserr.New("ValidationException", "invalid validity", nil), false},
+		{awserr.New(acmpca.ErrCodeMalformedCSRException, "invalid CSR", nil), false},
+		{fmt.Errorf("certificate request rejected"), false},
+	} {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func isPendingError(err error) bool {
+	_, ok := err.(*PendingError)
+	return ok
+}
+
+func newTestCSR(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"backend.example.com"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestIsCredentialsError(t *testing.T) {
+	const caArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+	server, err := mockacmpca.NewServer([]string{caArn}, mockacmpca.Config{AccessKeyID: "rotated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	SetEndpoint(ts.URL)
+	defer SetEndpoint("")
+
+	zero := 0
+	p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: caArn, Region: "us-east-1"}}, Options{Retry: RetryOptions{MaxRetries: &zero}})
+	_, err = p.DescribeCertificateAuthority(context.Background(), p.CertificateAuthorities()[0])
+	if !IsCredentialsError(err) {
+		t.Errorf("DescribeCertificateAuthority() with a revoked access key error = %v, want a credentials error", err)
+	}
+
+	server.SetConfig(mockacmpca.Config{})
+	unknown := CertificateAuthority{Arn: caArn[:len(caArn)-1] + "1", Region: "us-east-1"}
+	if _, err := p.DescribeCertificateAuthority(context.Background(), unknown); err == nil || IsCredentialsError(err) {
+		t.Errorf("DescribeCertificateAuthority() of an unknown CA error = %v, want another error", err)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	const (
+		caArn      = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000000"
+		retiredArn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000001"
+		otherArn   = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/00000000-0000-0000-0000-000000000002"
+	)
+	server, err := mockacmpca.NewServer([]string{caArn, retiredArn, otherArn}, mockacmpca.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	SetEndpoint(ts.URL)
+	defer SetEndpoint("")
+
+	cr := &certmanager.CertificateRequest{
+		Spec: certmanager.CertificateRequestSpec{
+			Request:  newTestCSR(t),
+			Duration: &metav1.Duration{Duration: 24 * time.Hour},
+		},
+	}
+	sign := func(arn string) *Certificate {
+		p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: arn, Region: "us-east-1"}}, Options{})
+		cert, err := p.Sign(context.Background(), cr)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return cert
+	}
+	retired, other := sign(retiredArn), sign(otherArn)
+
+	p := NewProvisioner(true, "access", "secret", []CertificateAuthority{{Arn: caArn, Region: "us-east-1"}}, Options{RetiredCAs: []string{retiredArn}})
+	if err := p.Revoke(context.Background(), retiredArn, retired.SerialNumber, "KEY_COMPROMISE"); err != nil {
+		t.Errorf("Revoke() of a certificate of a retired CA error = %v", err)
+	}
+	if !server.IsRevoked(retired.Arn) {
+		t.Error("the certificate of the retired CA was not revoked")
+	}
+
+	if err := p.Revoke(context.Background(), otherArn, other.SerialNumber, "KEY_COMPROMISE"); err == nil {
+		t.Error("Revoke() of a certificate of another CA succeeded")
+	}
+	if server.IsRevoked(other.Arn) {
+		t.Error("the certificate of another CA was revoked")
+	}
+}
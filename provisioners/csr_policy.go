@@ -0,0 +1,141 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// DefaultMinRSAKeySize is the minimum size of the RSA keys of the
+// certificate requests if not configured.
+const DefaultMinRSAKeySize = 2048
+
+// DefaultECDSACurves are the curves allowed for the ECDSA keys of the
+// certificate requests if not configured.
+var DefaultECDSACurves = []string{"P-256", "P-384", "P-521"}
+
+// weakSignatureAlgorithms are the CSR signature algorithms using a broken
+// hash function.
+var weakSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// CSRPolicy configures the checks of the certificate requests run before
+// they are sent to AWS Private CA. The zero value applies the defaults.
+type CSRPolicy struct {
+	// MinRSAKeySize is the minimum size in bits of RSA keys, defaults to
+	// DefaultMinRSAKeySize.
+	MinRSAKeySize int
+	// ECDSACurves are the allowed curves of ECDSA keys, defaults to
+	// DefaultECDSACurves.
+	ECDSACurves []string
+	// MaxSANs is the maximum number of SANs, unlimited if zero.
+	MaxSANs int
+	// ForbidWildcards rejects wildcard DNS names.
+	ForbidWildcards bool
+	// AllowCommonNameNotInSANs accepts common names that are not one of the
+	// SANs of the request.
+	AllowCommonNameNotInSANs bool
+}
+
+// validate checks the certificate request against the policy and the SPIFFE
+// trust domain, and returns all the violations.
+func (p CSRPolicy) validate(csr *x509.CertificateRequest, trustDomain string) error {
+	var errs []error
+
+	switch pub := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		minSize := p.MinRSAKeySize
+		if minSize == 0 {
+			minSize = DefaultMinRSAKeySize
+		}
+		if size := pub.N.BitLen(); size < minSize {
+			errs = append(errs, fmt.Errorf("RSA key size %d is smaller than %d bits", size, minSize))
+		}
+	case *ecdsa.PublicKey:
+		curves := p.ECDSACurves
+		if len(curves) == 0 {
+			curves = DefaultECDSACurves
+		}
+		if name := pub.Curve.Params().Name; !containsString(curves, name) {
+			errs = append(errs, fmt.Errorf("ECDSA curve %s is not allowed, use one of %s", name, strings.Join(curves, ", ")))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("key algorithm %s is not supported, use RSA or ECDSA", csr.PublicKeyAlgorithm))
+	}
+
+	if weakSignatureAlgorithms[csr.SignatureAlgorithm] {
+		errs = append(errs, fmt.Errorf("signature algorithm %s uses a weak hash function", csr.SignatureAlgorithm))
+	}
+
+	sans := SubjectAlternativeNames(csr)
+	if p.MaxSANs > 0 && len(sans) > p.MaxSANs {
+		errs = append(errs, fmt.Errorf("%d SANs exceed the maximum of %d", len(sans), p.MaxSANs))
+	}
+	seen := make(map[string]bool)
+	for _, san := range sans {
+		key := strings.ToLower(san)
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("duplicate SAN %q", san))
+		}
+		seen[key] = true
+	}
+
+	for _, name := range csr.DNSNames {
+		if !strings.Contains(name, "*") {
+			continue
+		}
+		if p.ForbidWildcards {
+			errs = append(errs, fmt.Errorf("wildcard DNS name %q is not allowed", name))
+			continue
+		}
+		labels := strings.Split(name, ".")
+		if labels[0] != "*" || strings.Count(name, "*") > 1 {
+			errs = append(errs, fmt.Errorf("invalid wildcard DNS name %q: only the leftmost label can be a wildcard", name))
+		} else if len(labels) < 3 {
+			errs = append(errs, fmt.Errorf("invalid wildcard DNS name %q: the wildcard must cover a subdomain of a registrable domain", name))
+		}
+	}
+
+	if cn := csr.Subject.CommonName; cn != "" && !p.AllowCommonNameNotInSANs && !seen[strings.ToLower(cn)] {
+		errs = append(errs, fmt.Errorf("common name %q is not one of the SANs", cn))
+	}
+
+	if err := validateSANs(csr, trustDomain); err != nil {
+		errs = append(errs, err.(utilerrors.Aggregate).Errors()...)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// rsaKey returns an RSA public key of the given size. The key is only used
+// for its size.
+func rsaKey(bits int) *rsa.PublicKey {
+	return &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), E: 65537}
+}
+
+func TestCSRPolicy(t *testing.T) {
+	p256 := &ecdsa.PublicKey{Curve: elliptic.P256()}
+	p224 := &ecdsa.PublicKey{Curve: elliptic.P224()}
+
+	tests := []struct {
+		name       string
+		policy     CSRPolicy
+		csr        x509.CertificateRequest
+		violations int
+	}{
+		{"rsa", CSRPolicy{}, x509.CertificateRequest{PublicKey: rsaKey(2048), DNSNames: []string{"example.com"}}, 0},
+		{"rsa too small", CSRPolicy{}, x509.CertificateRequest{PublicKey: rsaKey(1024)}, 1},
+		{"rsa configured size", CSRPolicy{MinRSAKeySize: 3072}, x509.CertificateRequest{PublicKey: rsaKey(2048)}, 1},
+		{"ecdsa", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256}, 0},
+		{"ecdsa weak curve", CSRPolicy{}, x509.CertificateRequest{PublicKey: p224}, 1},
+		{"ecdsa configured curves", CSRPolicy{ECDSACurves: []string{"P-384"}}, x509.CertificateRequest{PublicKey: p256}, 1},
+		{"ed25519", CSRPolicy{}, x509.CertificateRequest{PublicKey: ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), PublicKeyAlgorithm: x509.Ed25519}, 1},
+		{"sha1 signature", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, SignatureAlgorithm: x509.ECDSAWithSHA1}, 1},
+		{"max sans", CSRPolicy{MaxSANs: 2}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"a.example.com", "b.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, 1},
+		{"duplicate sans", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"example.com", "Example.com"}}, 1},
+		{"wildcard", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"*.example.com"}}, 0},
+		{"wildcard forbidden", CSRPolicy{ForbidWildcards: true}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"*.example.com"}}, 1},
+		{"wildcard not leftmost", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"www.*.example.com"}}, 1},
+		{"wildcard partial label", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"w*.example.com"}}, 1},
+		{"wildcard depth", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"*.*.example.com"}}, 1},
+		{"wildcard top level domain", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, DNSNames: []string{"*.com"}}, 1},
+		{"common name in sans", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, Subject: pkix.Name{CommonName: "Example.com"}, DNSNames: []string{"example.com"}}, 0},
+		{"common name not in sans", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"www.example.com"}}, 1},
+		{"common name not in sans allowed", CSRPolicy{AllowCommonNameNotInSANs: true}, x509.CertificateRequest{PublicKey: p256, Subject: pkix.Name{CommonName: "backend"}}, 0},
+		{"invalid san", CSRPolicy{}, x509.CertificateRequest{PublicKey: p256, EmailAddresses: []string{"admin"}}, 1},
+		{"all violations", CSRPolicy{MaxSANs: 1}, x509.CertificateRequest{PublicKey: rsaKey(1024), SignatureAlgorithm: x509.SHA1WithRSA,
+			Subject: pkix.Name{CommonName: "backend"}, DNSNames: []string{"*.com", "*.com"}}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.validate(&tt.csr, "")
+			violations := 0
+			if err != nil {
+				violations = len(err.(utilerrors.Aggregate).Errors())
+			}
+			if violations != tt.violations {
+				t.Errorf("validate() error = %v, want %d violations", err, tt.violations)
+			}
+		})
+	}
+}
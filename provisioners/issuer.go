@@ -0,0 +1,215 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"context"
+	"fmt"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IssuerError is returned by FromIssuer when the provisioner of an issuer
+// cannot be created. Reason is the reason of the condition of the issuer
+// that failed: PolicyValid for ReasonValidation, CredentialsValid otherwise.
+type IssuerError struct {
+	Reason  api.ConditionReason
+	Message string
+	Err     error
+}
+
+// Error implements error.
+func (e *IssuerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Message, e.Err)
+}
+
+// FromIssuer returns a provisioner configured with the spec of the given
+// issuer. The AWS access key and secret key are read from the secret
+// referenced by the issuer, with the region and the private CA ARN if they
+// are not set in the spec.
+//
+// Without a secret the default AWS credential chain of the process is used,
+// e.g. the IAM role of the controller, if allowAmbientCredentials is true.
+// Otherwise an IssuerError is returned: whoever can create an issuer would
+// be granted the permissions of the controller. With a secret the keys must
+// not be empty, the default chain is never used in its place.
+func FromIssuer(ctx context.Context, c client.Reader, iss *api.AWSPCAIssuer, allowAmbientCredentials bool) (*AWSPCAProvisioner, error) {
+	var accessKey string
+	var secretKey string
+	arn := iss.Spec.Arn
+	region := iss.Spec.Region
+
+	if iss.Spec.SecretRef == nil && !allowAmbientCredentials {
+		err := fmt.Errorf("the default AWS credentials of the controller are disabled, see --allow-ambient-credentials")
+		return nil, &IssuerError{Reason: api.ReasonValidation, Message: "spec.secretRef is required", Err: err}
+	}
+
+	if ref := iss.Spec.SecretRef; ref != nil {
+		var secret core.Secret
+		secretNamespaceName := types.NamespacedName{
+			Namespace: iss.Namespace,
+			Name:      ref.Name,
+		}
+
+		if err := c.Get(ctx, secretNamespaceName, &secret); err != nil {
+			reason := api.ReasonSecretError
+			if apierrors.IsNotFound(err) {
+				reason = api.ReasonNotFound
+			}
+			return nil, &IssuerError{Reason: reason, Message: "Failed to retrieve AWS secrets", Err: err}
+		}
+
+		value, ok := secret.Data[ref.AccessKeyRef.Key]
+		if !ok {
+			err := fmt.Errorf("secret %s does not contain key %s", secret.Name, ref.AccessKeyRef.Key)
+			return nil, &IssuerError{Reason: api.ReasonNotFound, Message: "Failed to retrieve AWS access key from secret", Err: err}
+		}
+		accessKey = string(value)
+		if accessKey == "" {
+			err := fmt.Errorf("key %s of secret %s is empty", ref.AccessKeyRef.Key, secret.Name)
+			return nil, &IssuerError{Reason: api.ReasonValidation, Message: "Failed to validate AWS access key from secret", Err: err}
+		}
+
+		value, ok = secret.Data[ref.SecretKeyRef.Key]
+		if !ok {
+			err := fmt.Errorf("secret %s does not contain key %s", secret.Name, ref.SecretKeyRef.Key)
+			return nil, &IssuerError{Reason: api.ReasonNotFound, Message: "Failed to retrieve AWS secret key from secret", Err: err}
+		}
+		secretKey = string(value)
+		if secretKey == "" {
+			err := fmt.Errorf("key %s of secret %s is empty", ref.SecretKeyRef.Key, secret.Name)
+			return nil, &IssuerError{Reason: api.ReasonValidation, Message: "Failed to validate AWS secret key from secret", Err: err}
+		}
+
+		if arn == "" && ref.ArnRef.Key != "" {
+			value, ok = secret.Data[ref.ArnRef.Key]
+			if !ok {
+				err := fmt.Errorf("secret %s does not contain key %s", secret.Name, ref.ArnRef.Key)
+				return nil, &IssuerError{Reason: api.ReasonNotFound, Message: "Failed to retrieve AWS Private CA ARN from secret", Err: err}
+			}
+			arn = string(value)
+		}
+
+		if region == "" && ref.RegionRef.Key != "" {
+			region = string(secret.Data[ref.RegionRef.Key])
+		}
+	}
+
+	// The private CAs listed in the spec replace the region and the ARN of
+	// the spec and of the secret.
+	var cas []CertificateAuthority
+	if len(iss.Spec.CertificateAuthorities) > 0 {
+		for _, ca := range iss.Spec.CertificateAuthorities {
+			region, err := caRegion(ca.Region, ca.Arn)
+			if err != nil {
+				return nil, &IssuerError{Reason: api.ReasonValidation, Message: "Failed to derive AWS region from AWS Private CA ARN", Err: err}
+			}
+			cas = append(cas, CertificateAuthority{
+				Arn:      ca.Arn,
+				Region:   region,
+				Weight:   pointer.Int32PtrDerefOr(ca.Weight, 0),
+				Draining: ca.State == api.CertificateAuthorityDraining,
+			})
+		}
+	} else {
+		region, err := caRegion(region, arn)
+		if err != nil {
+			return nil, &IssuerError{Reason: api.ReasonValidation, Message: "Failed to derive AWS region from AWS Private CA ARN", Err: err}
+		}
+		cas = append(cas, CertificateAuthority{Arn: arn, Region: region})
+	}
+
+	options := Options{TemplateArn: iss.Spec.TemplateArn}
+	if s := iss.Spec.Subject; s != nil {
+		subject, err := NewSubject(s.Organization, s.OrganizationalUnit, s.Country, s.CommonName)
+		if err != nil {
+			return nil, &IssuerError{Reason: api.ReasonValidation, Message: "Failed to validate spec.subject", Err: err}
+		}
+		options.Subject = subject
+	}
+	if iss.Spec.SPIFFE != nil {
+		options.SPIFFETrustDomain = iss.Spec.SPIFFE.TrustDomain
+	}
+	if c := iss.Spec.CSRPolicy; c != nil {
+		options.CSRPolicy = CSRPolicy{
+			MinRSAKeySize:            int(c.MinRSAKeySize),
+			ECDSACurves:              c.ECDSACurves,
+			MaxSANs:                  int(c.MaxSANs),
+			ForbidWildcards:          c.ForbidWildcards,
+			AllowCommonNameNotInSANs: c.AllowCommonNameNotInSANs,
+		}
+	}
+	if r := iss.Spec.RetryPolicy; r != nil {
+		if r.MaxRetries != nil {
+			maxRetries := int(*r.MaxRetries)
+			options.Retry.MaxRetries = &maxRetries
+		}
+		if r.MinRetryDelay != nil {
+			options.Retry.MinRetryDelay = r.MinRetryDelay.Duration
+		}
+		if r.MaxRetryDelay != nil {
+			options.Retry.MaxRetryDelay = r.MaxRetryDelay.Duration
+		}
+		if r.WaitInterval != nil {
+			options.Retry.WaitInterval = r.WaitInterval.Duration
+		}
+		options.Retry.WaitMaxAttempts = int(pointer.Int32PtrDerefOr(r.WaitMaxAttempts, 0))
+	}
+	options.RetiredCAs = RetiredCAs(iss.Status, cas)
+	return NewProvisioner(iss.Spec.SecretRef != nil, accessKey, secretKey, cas, options), nil
+}
+
+// RetiredCAs returns the ARNs of the private CAs of the issuer that are no
+// longer used, with the given current CAs: the CAs previously retired or
+// reported in its status, but not current.
+func RetiredCAs(status api.AWSPCAIssuerStatus, current []CertificateAuthority) []string {
+	seen := make(map[string]bool)
+	for _, ca := range current {
+		seen[ca.Arn] = true
+	}
+	var retired []string
+	add := func(arn string) {
+		if !seen[arn] {
+			seen[arn] = true
+			retired = append(retired, arn)
+		}
+	}
+	for _, arn := range status.RetiredCertificateAuthorities {
+		add(arn)
+	}
+	for _, ca := range status.CertificateAuthorities {
+		add(ca.Arn)
+	}
+	return retired
+}
+
+// caRegion returns the given region, or the region of the private CA ARN if
+// it is empty.
+func caRegion(region, arn string) (string, error) {
+	if region != "" {
+		return region, nil
+	}
+	parsed, err := api.ParseCAArn(arn)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Region, nil
+}
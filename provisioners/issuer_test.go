@@ -0,0 +1,105 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	api "github.com/awspca-issuer/api/v1beta1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFromIssuerAmbientCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme)
+	iss := &api.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+		Spec: api.AWSPCAIssuerSpec{
+			Arn: "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555",
+		},
+	}
+
+	_, err := FromIssuer(context.Background(), c, iss, false)
+	ierr, ok := err.(*IssuerError)
+	if !ok || ierr.Reason != api.ReasonValidation {
+		t.Errorf("FromIssuer() without a secret error = %v, want a %s IssuerError", err, api.ReasonValidation)
+	}
+
+	p, err := FromIssuer(context.Background(), c, iss, true)
+	if err != nil {
+		t.Fatalf("FromIssuer() with ambient credentials error = %v", err)
+	}
+	if p.static {
+		t.Errorf("FromIssuer() with ambient credentials set static credentials %q", p.accesskey)
+	}
+}
+
+func TestFromIssuerEmptyCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme, &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "aws"},
+		Data:       map[string][]byte{"accesskey": []byte(""), "secretkey": []byte("secret")},
+	})
+	iss := &api.AWSPCAIssuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "issuer"},
+		Spec: api.AWSPCAIssuerSpec{
+			Arn: "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555",
+			SecretRef: &api.AWSCredentialsSecretReference{
+				Name:         "aws",
+				AccessKeyRef: api.SecretKeySelector{Key: "accesskey"},
+				SecretKeyRef: api.SecretKeySelector{Key: "secretkey"},
+			},
+		},
+	}
+
+	// The default AWS credential chain must not replace the empty access
+	// key of the secret, whether the ambient credentials are enabled or not.
+	for _, allowAmbientCredentials := range []bool{false, true} {
+		_, err := FromIssuer(context.Background(), c, iss, allowAmbientCredentials)
+		ierr, ok := err.(*IssuerError)
+		if !ok || ierr.Reason != api.ReasonValidation {
+			t.Errorf("FromIssuer(%v) with an empty access key error = %v, want a %s IssuerError", allowAmbientCredentials, err, api.ReasonValidation)
+		}
+	}
+
+	p := NewProvisioner(true, "", "", nil, Options{})
+	if _, err := p.client("us-east-1"); err == nil {
+		t.Error("client() with empty static credentials did not fail")
+	}
+}
+
+func TestRetiredCAs(t *testing.T) {
+	status := api.AWSPCAIssuerStatus{
+		CertificateAuthorities:        []api.CertificateAuthorityStatus{{Arn: "a"}, {Arn: "b"}},
+		RetiredCertificateAuthorities: []string{"c", "a"},
+	}
+	got := RetiredCAs(status, []CertificateAuthority{{Arn: "a"}})
+	if want := []string{"c", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RetiredCAs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// spiffeScheme is the URI scheme of SPIFFE IDs.
+const spiffeScheme = "spiffe"
+
+var (
+	// spiffeTrustDomainRegexp matches the characters allowed in the trust
+	// domain of a SPIFFE ID.
+	spiffeTrustDomainRegexp = regexp.MustCompile(`^[a-z0-9._-]+$`)
+	// spiffePathSegmentRegexp matches the characters allowed in the path
+	// segments of a SPIFFE ID.
+	spiffePathSegmentRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// SubjectAlternativeNames returns all the SANs of the certificate request:
+// DNS names, IP addresses, email addresses and URIs, in that order.
+func SubjectAlternativeNames(csr *x509.CertificateRequest) []string {
+	return sanList(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs)
+}
+
+// sanList returns the given SANs of a certificate or certificate request as
+// strings.
+func sanList(dnsNames []string, ips []net.IP, emails []string, uris []*url.URL) []string {
+	sans := append([]string{}, dnsNames...)
+	for _, ip := range ips {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, emails...)
+	for _, u := range uris {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// validateSANs checks the email and URI SANs of the certificate request. A
+// request may contain at most one SPIFFE ID, which must belong to the given
+// trust domain if it is not empty. All the violations are returned.
+func validateSANs(csr *x509.CertificateRequest, trustDomain string) error {
+	var errs []error
+	for _, email := range csr.EmailAddresses {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			errs = append(errs, fmt.Errorf("invalid email SAN %q", email))
+		}
+	}
+
+	spiffeIDs := 0
+	for _, u := range csr.URIs {
+		if u.Scheme == "" {
+			errs = append(errs, fmt.Errorf("invalid URI SAN %q: not an absolute URI", u.String()))
+			continue
+		}
+		// The scheme is always lowercase once parsed.
+		if u.Scheme != spiffeScheme {
+			continue
+		}
+		if err := validateSPIFFEID(u, trustDomain); err != nil {
+			errs = append(errs, err)
+		}
+		spiffeIDs++
+	}
+	if spiffeIDs > 1 {
+		errs = append(errs, fmt.Errorf("certificate request contains %d SPIFFE IDs, at most one is allowed", spiffeIDs))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateSPIFFEID checks that the URI is a valid SPIFFE ID, as defined by
+// the SPIFFE ID specification, in the given trust domain if not empty.
+func validateSPIFFEID(u *url.URL, trustDomain string) error {
+	id := u.String()
+	switch {
+	case u.Opaque != "":
+		return fmt.Errorf("invalid SPIFFE ID %q: trust domain is missing", id)
+	case u.User != nil:
+		return fmt.Errorf("invalid SPIFFE ID %q: user info is not allowed", id)
+	case u.Port() != "":
+		return fmt.Errorf("invalid SPIFFE ID %q: port is not allowed", id)
+	case u.RawQuery != "" || u.ForceQuery:
+		return fmt.Errorf("invalid SPIFFE ID %q: query is not allowed", id)
+	case u.Fragment != "":
+		return fmt.Errorf("invalid SPIFFE ID %q: fragment is not allowed", id)
+	case u.Host == "" || !spiffeTrustDomainRegexp.MatchString(u.Host):
+		return fmt.Errorf("invalid SPIFFE ID %q: invalid trust domain", id)
+	}
+
+	if u.Path != "" {
+		for _, segment := range strings.Split(strings.TrimPrefix(u.Path, "/"), "/") {
+			if segment == "." || segment == ".." || !spiffePathSegmentRegexp.MatchString(segment) {
+				return fmt.Errorf("invalid SPIFFE ID %q: invalid path segment %q", id, segment)
+			}
+		}
+	}
+
+	if trustDomain != "" && u.Host != trustDomain {
+		return fmt.Errorf("SPIFFE ID %q does not belong to trust domain %q", id, trustDomain)
+	}
+	return nil
+}
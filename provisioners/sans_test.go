@@ -0,0 +1,92 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func mustParseURIs(t *testing.T, uris ...string) []*url.URL {
+	var out []*url.URL
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+func TestSubjectAlternativeNames(t *testing.T) {
+	csr := &x509.CertificateRequest{
+		DNSNames:       []string{"localhost", "example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1")},
+		EmailAddresses: []string{"admin@example.com"},
+		URIs:           mustParseURIs(t, "spiffe://example.org/ns/default/sa/backend"),
+	}
+	want := []string{"localhost", "example.com", "127.0.0.1", "admin@example.com", "spiffe://example.org/ns/default/sa/backend"}
+	if got := SubjectAlternativeNames(csr); !reflect.DeepEqual(got, want) {
+		t.Errorf("SubjectAlternativeNames() = %v, want %v", got, want)
+	}
+
+	// A CSR with only a SPIFFE ID uses it as subject.
+	csr = &x509.CertificateRequest{URIs: mustParseURIs(t, "spiffe://example.org/backend")}
+	if got := generateSubject(SubjectAlternativeNames(csr)); got != "spiffe://example.org/backend" {
+		t.Errorf("generateSubject() = %q, want the SPIFFE ID", got)
+	}
+}
+
+func TestValidateSANs(t *testing.T) {
+	tests := []struct {
+		name        string
+		emails      []string
+		uris        []string
+		trustDomain string
+		wantErr     bool
+	}{
+		{"none", nil, nil, "", false},
+		{"email", []string{"admin@example.com"}, nil, "", false},
+		{"invalid email", []string{"Admin <admin@example.com>"}, nil, "", true},
+		{"urn", nil, []string{"urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6"}, "example.org", false},
+		{"spiffe", nil, []string{"spiffe://example.org/ns/default/sa/backend"}, "", false},
+		{"spiffe trust domain", nil, []string{"spiffe://example.org/ns/default/sa/backend"}, "example.org", false},
+		{"spiffe trust domain only", nil, []string{"spiffe://example.org"}, "example.org", false},
+		{"spiffe other trust domain", nil, []string{"spiffe://other.org/backend"}, "example.org", true},
+		{"spiffe uppercase trust domain", nil, []string{"spiffe://Example.org/backend"}, "", true},
+		{"spiffe port", nil, []string{"spiffe://example.org:8080/backend"}, "", true},
+		{"spiffe user", nil, []string{"spiffe://user@example.org/backend"}, "", true},
+		{"spiffe query", nil, []string{"spiffe://example.org/backend?a=b"}, "", true},
+		{"spiffe fragment", nil, []string{"spiffe://example.org/backend#a"}, "", true},
+		{"spiffe empty segment", nil, []string{"spiffe://example.org/ns//backend"}, "", true},
+		{"spiffe trailing slash", nil, []string{"spiffe://example.org/backend/"}, "", true},
+		{"spiffe dot segment", nil, []string{"spiffe://example.org/ns/../backend"}, "", true},
+		{"spiffe opaque", nil, []string{"spiffe:example.org"}, "", true},
+		{"two spiffe ids", nil, []string{"spiffe://example.org/a", "spiffe://example.org/b"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := &x509.CertificateRequest{EmailAddresses: tt.emails, URIs: mustParseURIs(t, tt.uris...)}
+			if err := validateSANs(csr, tt.trustDomain); (err != nil) != tt.wantErr {
+				t.Errorf("validateSANs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
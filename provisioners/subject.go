@@ -0,0 +1,229 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/acmpca"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+)
+
+// maxCommonNameLength is the maximum length of the common name of a
+// certificate, ub-common-name in RFC 5280.
+const maxCommonNameLength = 64
+
+// certificateNameAnnotation is set by cert-manager on the CertificateRequests
+// created for a Certificate.
+const certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+// Subject configures the subject of the issued certificates. It is only sent
+// to AWS Private CA with an API passthrough template.
+type Subject struct {
+	Organization       string
+	OrganizationalUnit string
+	Country            string
+
+	commonName *template.Template
+}
+
+// NewSubject returns a subject with the given attributes. The common name is
+// a text/template executed with the namespace and name of the
+// CertificateRequest and the SANs of its CSR; if empty, the common name of
+// the CSR or its first SAN is used.
+func NewSubject(organization, organizationalUnit, country, commonName string) (*Subject, error) {
+	s := &Subject{
+		Organization:       organization,
+		OrganizationalUnit: organizationalUnit,
+		Country:            country,
+	}
+	if commonName != "" {
+		tmpl, err := template.New("commonName").Option("missingkey=error").Parse(commonName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid common name template: %v", err)
+		}
+		s.commonName = tmpl
+	}
+	return s, nil
+}
+
+// subjectData is the data the common name template is executed with.
+type subjectData struct {
+	// Namespace and Name of the CertificateRequest.
+	Namespace string
+	Name      string
+	// CertificateName is the name of the Certificate that created the
+	// CertificateRequest, if any.
+	CertificateName string
+	// CommonName is the common name of the CSR.
+	CommonName string
+	// SAN is the first SAN of the CSR that is not localhost.
+	SAN            string
+	DNSNames       []string
+	IPAddresses    []string
+	EmailAddresses []string
+	URIs           []string
+}
+
+// asn1Subject is the subject of a certificate in the ApiPassthrough
+// parameter of IssueCertificate.
+type asn1Subject struct {
+	_ struct{} `type:"structure"`
+
+	CommonName         *string `type:"string"`
+	Country            *string `type:"string"`
+	Organization       *string `type:"string"`
+	OrganizationalUnit *string `type:"string"`
+}
+
+// String returns the subject as an RFC 2253 distinguished name, or an empty
+// string if nil.
+func (s *asn1Subject) String() string {
+	if s == nil {
+		return ""
+	}
+	var name pkix.Name
+	name.CommonName = aws.StringValue(s.CommonName)
+	if s.Country != nil {
+		name.Country = []string{*s.Country}
+	}
+	if s.Organization != nil {
+		name.Organization = []string{*s.Organization}
+	}
+	if s.OrganizationalUnit != nil {
+		name.OrganizationalUnit = []string{*s.OrganizationalUnit}
+	}
+	return name.String()
+}
+
+// apiPassthrough is the ApiPassthrough parameter of IssueCertificate.
+type apiPassthrough struct {
+	_ struct{} `type:"structure"`
+
+	Subject *asn1Subject `type:"structure"`
+}
+
+// issueCertificateInput is acmpca.IssueCertificateInput with the
+// ApiPassthrough parameter, which is not supported by the version of the AWS
+// SDK used by the controller. It must keep all the fields of
+// acmpca.IssueCertificateInput.
+//
+// TODO: use acmpca.IssueCertificateInput once aws-sdk-go is upgraded to a
+// release supporting ApiPassthrough.
+type issueCertificateInput struct {
+	_ struct{} `type:"structure"`
+
+	ApiPassthrough          *apiPassthrough  `type:"structure"`
+	CertificateAuthorityArn *string          `type:"string"`
+	Csr                     []byte           `type:"blob"`
+	IdempotencyToken        *string          `type:"string"`
+	SigningAlgorithm        *string          `type:"string"`
+	TemplateArn             *string          `type:"string"`
+	Validity                *acmpca.Validity `type:"structure"`
+}
+
+// isAPIPassthroughTemplate returns true if the subject of the certificate
+// request can be set with the given template, e.g.
+// arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1
+func isAPIPassthroughTemplate(templateArn string) bool {
+	return strings.Contains(templateArn, "_APIPassthrough/") || strings.Contains(templateArn, "_APICSRPassthrough/")
+}
+
+// subject returns the subject of the certificate issued for the given
+// request.
+func (s *Subject) subject(cr *certmanager.CertificateRequest, csr *x509.CertificateRequest) (*asn1Subject, error) {
+	sans := SubjectAlternativeNames(csr)
+	commonName := csr.Subject.CommonName
+	if commonName == "" {
+		commonName = generateSubject(sans)
+	}
+
+	if s != nil && s.commonName != nil {
+		data := subjectData{
+			Namespace:       cr.Namespace,
+			Name:            cr.Name,
+			CertificateName: cr.Annotations[certificateNameAnnotation],
+			CommonName:      csr.Subject.CommonName,
+			SAN:             generateSubject(sans),
+			DNSNames:        csr.DNSNames,
+			EmailAddresses:  csr.EmailAddresses,
+		}
+		for _, ip := range csr.IPAddresses {
+			data.IPAddresses = append(data.IPAddresses, ip.String())
+		}
+		for _, u := range csr.URIs {
+			data.URIs = append(data.URIs, u.String())
+		}
+
+		var buf bytes.Buffer
+		if err := s.commonName.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("error executing common name template: %v", err)
+		}
+		commonName = buf.String()
+	}
+	if len(commonName) > maxCommonNameLength {
+		return nil, fmt.Errorf("common name %q is longer than %d characters", commonName, maxCommonNameLength)
+	}
+
+	subject := &asn1Subject{CommonName: aws.String(commonName)}
+	if s != nil {
+		if s.Country != "" {
+			subject.Country = aws.String(s.Country)
+		}
+		if s.Organization != "" {
+			subject.Organization = aws.String(s.Organization)
+		}
+		if s.OrganizationalUnit != "" {
+			subject.OrganizationalUnit = aws.String(s.OrganizationalUnit)
+		}
+	}
+	return subject, nil
+}
+
+// issueCertificateWithSubject calls IssueCertificate with the given subject
+// in the ApiPassthrough parameter.
+func issueCertificateWithSubject(ctx context.Context, svc *acmpca.ACMPCA, in *acmpca.IssueCertificateInput, subject *asn1Subject) (*acmpca.IssueCertificateOutput, error) {
+	// The SDK only validates the inputs it knows.
+	if err := in.Validate(); err != nil {
+		return nil, err
+	}
+	op := &request.Operation{
+		Name:       "IssueCertificate",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	input := &issueCertificateInput{
+		ApiPassthrough:          &apiPassthrough{Subject: subject},
+		CertificateAuthorityArn: in.CertificateAuthorityArn,
+		Csr:                     in.Csr,
+		IdempotencyToken:        in.IdempotencyToken,
+		SigningAlgorithm:        in.SigningAlgorithm,
+		TemplateArn:             in.TemplateArn,
+		Validity:                in.Validity,
+	}
+	output := &acmpca.IssueCertificateOutput{}
+	req := svc.NewRequest(op, input, output)
+	req.SetContext(ctx)
+	return output, req.Send()
+}
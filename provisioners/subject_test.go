@@ -0,0 +1,155 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/acmpca"
+	certmanager "github.com/awspca-issuer/certmanager/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSubject(t *testing.T) {
+	cr := &certmanager.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "backend-1234",
+			Annotations: map[string]string{certificateNameAnnotation: "backend"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		commonName string
+		csr        *x509.CertificateRequest
+		want       string
+		wantErr    bool
+	}{
+		{"csr common name", "", &x509.CertificateRequest{Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"www.example.com"}}, "example.com", false},
+		{"no common name", "", &x509.CertificateRequest{DNSNames: []string{"localhost", "www.example.com"}}, "www.example.com", false},
+		{"no common name spiffe", "", &x509.CertificateRequest{URIs: mustParseURIs(t, "spiffe://example.org/backend")}, "spiffe://example.org/backend", false},
+		{"no common name no sans", "", &x509.CertificateRequest{}, "awspca-issuer-certificate", false},
+		{"template", "{{ .CertificateName }}.{{ .Namespace }}", &x509.CertificateRequest{DNSNames: []string{"www.example.com"}}, "backend.default", false},
+		{"template san", "{{ .SAN }}", &x509.CertificateRequest{Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"www.example.com"}}, "www.example.com", false},
+		{"template dns names", "{{ index .DNSNames 1 }}", &x509.CertificateRequest{DNSNames: []string{"a.example.com", "b.example.com"}}, "b.example.com", false},
+		{"template csr common name", "{{ .CommonName }}-{{ .Name }}", &x509.CertificateRequest{Subject: pkix.Name{CommonName: "example"}}, "example-backend-1234", false},
+		{"template error", "{{ index .DNSNames 1 }}", &x509.CertificateRequest{DNSNames: []string{"a.example.com"}}, "", true},
+		{"too long", "", &x509.CertificateRequest{DNSNames: []string{strings.Repeat("a", 60) + ".example.com"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSubject("Example", "Engineering", "US", tt.commonName)
+			if err != nil {
+				t.Fatalf("NewSubject() error = %v", err)
+			}
+			got, err := s.subject(cr, tt.csr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("subject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if aws.StringValue(got.CommonName) != tt.want {
+				t.Errorf("subject() common name = %q, want %q", aws.StringValue(got.CommonName), tt.want)
+			}
+			if aws.StringValue(got.Organization) != "Example" || aws.StringValue(got.OrganizationalUnit) != "Engineering" || aws.StringValue(got.Country) != "US" {
+				t.Errorf("subject() = %+v, want the configured attributes", got)
+			}
+		})
+	}
+
+	// Without a subject configuration only the common name is set.
+	var s *Subject
+	got, err := s.subject(cr, &x509.CertificateRequest{DNSNames: []string{"www.example.com"}})
+	if err != nil {
+		t.Fatalf("subject() error = %v", err)
+	}
+	if aws.StringValue(got.CommonName) != "www.example.com" || got.Organization != nil || got.Country != nil {
+		t.Errorf("subject() = %+v, want only the common name", got)
+	}
+	if got.String() != "CN=www.example.com" {
+		t.Errorf("String() = %q, want %q", got.String(), "CN=www.example.com")
+	}
+	s, _ = NewSubject("Example", "Engineering", "US", "")
+	got, _ = s.subject(cr, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "example.com"}})
+	if want := "CN=example.com,OU=Engineering,O=Example,C=US"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+
+	if _, err := NewSubject("", "", "", "{{ .Name"); err == nil {
+		t.Error("NewSubject() accepted an invalid template")
+	}
+}
+
+func TestIssueCertificateInputJSON(t *testing.T) {
+	input := &issueCertificateInput{
+		ApiPassthrough: &apiPassthrough{Subject: &asn1Subject{
+			CommonName:   aws.String("example.com"),
+			Organization: aws.String("Example"),
+		}},
+		CertificateAuthorityArn: aws.String("arn"),
+		TemplateArn:             aws.String("arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1"),
+	}
+	data, err := jsonutil.BuildJSON(input)
+	if err != nil {
+		t.Fatalf("BuildJSON() error = %v", err)
+	}
+	want := `"ApiPassthrough":{"Subject":{"CommonName":"example.com","Organization":"Example"}}`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("BuildJSON() = %s, want it to contain %s", data, want)
+	}
+}
+
+func TestIssueCertificateInputFields(t *testing.T) {
+	sdk := reflect.TypeOf(acmpca.IssueCertificateInput{})
+	ours := reflect.TypeOf(issueCertificateInput{})
+	for i := 0; i < sdk.NumField(); i++ {
+		f := sdk.Field(i)
+		if f.Name == "_" {
+			continue
+		}
+		g, ok := ours.FieldByName(f.Name)
+		if !ok {
+			t.Errorf("issueCertificateInput has no %s field", f.Name)
+			continue
+		}
+		if g.Type != f.Type || g.Tag.Get("type") != f.Tag.Get("type") {
+			t.Errorf("issueCertificateInput.%s is %s `type:%q`, want %s `type:%q`",
+				f.Name, g.Type, g.Tag.Get("type"), f.Type, f.Tag.Get("type"))
+		}
+	}
+}
+
+func TestIsAPIPassthroughTemplate(t *testing.T) {
+	for arn, want := range map[string]bool{
+		"": false,
+		"arn:aws:acm-pca:::template/EndEntityCertificate/V1":                        false,
+		"arn:aws:acm-pca:::template/EndEntityCertificate_CSRPassthrough/V1":         false,
+		"arn:aws:acm-pca:::template/EndEntityCertificate_APIPassthrough/V1":         true,
+		"arn:aws:acm-pca:::template/BlankEndEntityCertificate_APICSRPassthrough/V1": true,
+	} {
+		if got := isAPIPassthroughTemplate(arn); got != want {
+			t.Errorf("isAPIPassthroughTemplate(%q) = %v, want %v", arn, got, want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpTracesPath is the default path of the traces on an OTLP/HTTP
+// collector.
+const otlpTracesPath = "/v1/traces"
+
+// NewExporter returns the span exporter configured by the value of
+// --trace-exporter:
+//   - "stdout" writes the spans as OTLP JSON lines to the standard output,
+//   - "otlp:<url>" sends the spans to the OTLP/HTTP collector at the given
+//     URL, e.g. otlp:http://otel-collector:4318.
+//
+// An empty value disables tracing, and nil is returned.
+func NewExporter(value string) (sdktrace.SpanExporter, error) {
+	switch {
+	case value == "":
+		return nil, nil
+	case value == "stdout":
+		return NewWriterExporter(os.Stdout), nil
+	case strings.HasPrefix(value, "otlp:"):
+		u, err := url.Parse(strings.TrimPrefix(value, "otlp:"))
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid trace exporter %q: the collector must be an http or https URL", value)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = otlpTracesPath
+		}
+		return NewOTLPExporter(u.String()), nil
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q: must be stdout or otlp:<url>", value)
+	}
+}
+
+// writerExporter writes the spans as OTLP JSON lines.
+type writerExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterExporter returns an exporter writing every batch of spans to w as
+// a line of OTLP JSON.
+func NewWriterExporter(w io.Writer) sdktrace.SpanExporter {
+	return &writerExporter{enc: json.NewEncoder(w)}
+}
+
+// ExportSpans implements SpanExporter.
+func (e *writerExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(newExportRequest(spans))
+}
+
+// Shutdown implements SpanExporter.
+func (e *writerExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// otlpExporter sends the spans to an OTLP/HTTP collector. The JSON encoding
+// of OTLP is used, as it does not require the gRPC and protobuf
+// dependencies of the OTLP exporters of OpenTelemetry.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewOTLPExporter returns an exporter sending the spans to the OTLP/HTTP
+// traces endpoint at the given URL.
+func NewOTLPExporter(url string) sdktrace.SpanExporter {
+	return &otlpExporter{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ExportSpans implements SpanExporter.
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	body, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export spans: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Shutdown implements SpanExporter.
+func (e *otlpExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The OTLP JSON encoding of the spans, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+
+type exportRequest struct {
+	ResourceSpans []*resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   otlpResource  `json:"resource"`
+	ScopeSpans []*scopeSpans `json:"scopeSpans"`
+	SchemaURL  string        `json:"schemaUrl,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeSpans struct {
+	Scope     scope  `json:"scope"`
+	Spans     []span `json:"spans"`
+	SchemaURL string `json:"schemaUrl,omitempty"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type span struct {
+	TraceID                string     `json:"traceId"`
+	SpanID                 string     `json:"spanId"`
+	ParentSpanID           string     `json:"parentSpanId,omitempty"`
+	Name                   string     `json:"name"`
+	Kind                   int        `json:"kind"`
+	StartTimeUnixNano      string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano        string     `json:"endTimeUnixNano"`
+	Attributes             []keyValue `json:"attributes,omitempty"`
+	DroppedAttributesCount int        `json:"droppedAttributesCount,omitempty"`
+	Events                 []event    `json:"events,omitempty"`
+	DroppedEventsCount     int        `json:"droppedEventsCount,omitempty"`
+	Status                 status     `json:"status"`
+}
+
+type event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// The status codes of OTLP, which differ from the codes of OpenTelemetry.
+const (
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// newExportRequest returns the OTLP request exporting the given spans,
+// grouped by resource and instrumentation library.
+func newExportRequest(spans []sdktrace.ReadOnlySpan) *exportRequest {
+	req := &exportRequest{ResourceSpans: []*resourceSpans{}}
+	resources := make(map[attribute.Distinct]*resourceSpans)
+	scopes := make(map[attribute.Distinct]map[instrumentation.Library]*scopeSpans)
+	for _, s := range spans {
+		res := s.Resource()
+		if res == nil {
+			res = resource.Empty()
+		}
+		key := res.Equivalent()
+		rs, ok := resources[key]
+		if !ok {
+			rs = &resourceSpans{
+				Resource:  otlpResource{Attributes: keyValues(res.Attributes())},
+				SchemaURL: res.SchemaURL(),
+			}
+			resources[key] = rs
+			scopes[key] = make(map[instrumentation.Library]*scopeSpans)
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+		lib := s.InstrumentationLibrary()
+		ss, ok := scopes[key][lib]
+		if !ok {
+			ss = &scopeSpans{Scope: scope{Name: lib.Name, Version: lib.Version}, SchemaURL: lib.SchemaURL}
+			scopes[key][lib] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, newSpan(s))
+	}
+	return req
+}
+
+// newSpan returns the OTLP span of the given span.
+func newSpan(s sdktrace.ReadOnlySpan) span {
+	out := span{
+		TraceID:                s.SpanContext().TraceID().String(),
+		SpanID:                 s.SpanContext().SpanID().String(),
+		Name:                   s.Name(),
+		Kind:                   int(s.SpanKind()),
+		StartTimeUnixNano:      unixNano(s.StartTime()),
+		EndTimeUnixNano:        unixNano(s.EndTime()),
+		Attributes:             keyValues(s.Attributes()),
+		DroppedAttributesCount: s.DroppedAttributes(),
+		DroppedEventsCount:     s.DroppedEvents(),
+	}
+	if s.Parent().HasSpanID() {
+		out.ParentSpanID = s.Parent().SpanID().String()
+	}
+	for _, e := range s.Events() {
+		out.Events = append(out.Events, event{
+			TimeUnixNano: unixNano(e.Time),
+			Name:         e.Name,
+			Attributes:   keyValues(e.Attributes),
+		})
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		out.Status.Code = otlpStatusOk
+	case codes.Error:
+		out.Status.Code = otlpStatusError
+		out.Status.Message = s.Status().Description
+	}
+	return out
+}
+
+// keyValues returns the OTLP attributes of the given attributes. Arrays are
+// sent as their string representation, the spans of the controller do not
+// have any.
+func keyValues(attrs []attribute.KeyValue) []keyValue {
+	var out []keyValue
+	for _, kv := range attrs {
+		var v anyValue
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			b := kv.Value.AsBool()
+			v.BoolValue = &b
+		case attribute.INT64:
+			i := strconv.FormatInt(kv.Value.AsInt64(), 10)
+			v.IntValue = &i
+		case attribute.FLOAT64:
+			f := kv.Value.AsFloat64()
+			v.DoubleValue = &f
+		default:
+			s := kv.Value.Emit()
+			v.StringValue = &s
+		}
+		out = append(out, keyValue{Key: string(kv.Key), Value: v})
+	}
+	return out
+}
+
+// unixNano returns the time in nanoseconds since the epoch, as a string as
+// 64 bit integers are encoded in JSON by OTLP.
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
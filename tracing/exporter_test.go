@@ -0,0 +1,122 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewExporter(t *testing.T) {
+	for value, want := range map[string]string{
+		"":                              "",
+		"stdout":                        "",
+		"otlp:http://collector:4318":    "http://collector:4318/v1/traces",
+		"otlp:https://collector/traces": "https://collector/traces",
+		"otlp:collector:4318":           "error",
+		"otlp:":                         "error",
+		"jaeger":                        "error",
+	} {
+		e, err := NewExporter(value)
+		if (err != nil) != (want == "error") {
+			t.Errorf("NewExporter(%q) error = %v", value, err)
+			continue
+		}
+		if o, ok := e.(*otlpExporter); ok && o.url != want {
+			t.Errorf("NewExporter(%q) url = %q, want %q", value, o.url, want)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var got exportRequest
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path != otlpTracesPath || json.Unmarshal(body, &got) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter("otlp:" + server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter), sdktrace.WithResource(resource.NewSchemaless(NameKey.String("test"))))
+	tracer := provider.Tracer(instrumentationName)
+
+	ctx, parent := tracer.Start(context.Background(), "CertificateRequest.Reconcile")
+	_, child := tracer.Start(ctx, "IssueCertificate")
+	child.SetAttributes(CertificateRequestUIDKey.String("uid"), CertificateArnKey.String("certificate-arn"))
+	End(child, errors.New("throttled"))
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("exported %+v, want a single span", got)
+	}
+	if attrs := got.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || *attrs[0].Value.StringValue != "test" {
+		t.Errorf("resource attributes = %+v, want the resource of the provider", attrs)
+	}
+	if name := got.ResourceSpans[0].ScopeSpans[0].Scope.Name; name != instrumentationName {
+		t.Errorf("scope = %q, want %q", name, instrumentationName)
+	}
+	s := got.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if s.Name != "IssueCertificate" || s.TraceID != parent.SpanContext().TraceID().String() || s.ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("span = %+v, want the IssueCertificate child of %s", s, parent.SpanContext().SpanID())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range s.Attributes {
+		attrs[kv.Key] = *kv.Value.StringValue
+	}
+	if attrs[string(CertificateRequestUIDKey)] != "uid" || attrs[string(CertificateArnKey)] != "certificate-arn" {
+		t.Errorf("span attributes = %v, want the UID and certificate ARN", attrs)
+	}
+	if s.Status.Code != otlpStatusError || s.Status.Message != "throttled" || len(s.Events) != 1 {
+		t.Errorf("span status = %+v with %d events, want the recorded error", s.Status, len(s.Events))
+	}
+	if s.StartTimeUnixNano == "0" || s.EndTimeUnixNano == "0" {
+		t.Errorf("span times = %s, %s, want the start and end of the span", s.StartTimeUnixNano, s.EndTimeUnixNano)
+	}
+}
+
+func TestOTLPExporterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, span := sdktrace.NewTracerProvider().Tracer(instrumentationName).Start(context.Background(), "span")
+	span.End()
+	err := NewOTLPExporter(server.URL).ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{span.(sdktrace.ReadOnlySpan)})
+	if err == nil {
+		t.Error("ExportSpans() did not fail on a 503 of the collector")
+	}
+}
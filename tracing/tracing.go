@@ -0,0 +1,97 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing contains the OpenTelemetry tracing of the AWSPCA issuer.
+// The spans are exported with the exporter configured with
+// --trace-exporter, and are dropped if tracing is disabled.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// instrumentationName is the name of the tracer of the controller.
+const instrumentationName = "github.com/awspca-issuer"
+
+// serviceName is the service.name of the exported spans.
+const serviceName = "awspca-issuer"
+
+// The attributes of the spans.
+const (
+	// NamespaceKey is the namespace of the reconciled resource.
+	NamespaceKey = attribute.Key("k8s.namespace.name")
+	// NameKey is the name of the reconciled resource.
+	NameKey = attribute.Key("k8s.resource.name")
+	// CertificateRequestUIDKey is the UID of the CertificateRequest being
+	// signed.
+	CertificateRequestUIDKey = attribute.Key("certmanager.certificaterequest.uid")
+	// CAArnKey is the ARN of the private CA called.
+	CAArnKey = attribute.Key("aws.acmpca.certificate_authority_arn")
+	// CertificateArnKey is the ARN of the certificate issued by AWS Private
+	// CA, as found in CloudTrail.
+	CertificateArnKey = attribute.Key("aws.acmpca.certificate_arn")
+)
+
+// Setup registers the tracer provider exporting the spans with the exporter
+// configured by the value of --trace-exporter, see NewExporter. It returns
+// the function flushing the pending spans and stopping the export. An empty
+// value disables tracing, the spans are then dropped.
+func Setup(value string) (func(context.Context) error, error) {
+	exporter, err := NewExporter(value)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the given name and attributes, child of the span
+// of the given context if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartReconcile starts the span of a reconciliation of the given controller.
+func StartReconcile(ctx context.Context, controller string, name types.NamespacedName) (context.Context, trace.Span) {
+	return Start(ctx, controller+".Reconcile", NamespaceKey.String(name.Namespace), NameKey.String(name.Name))
+}
+
+// End ends the span, recording the error if not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SetAttributes sets the given attributes on the span of the context, if
+// any.
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}